@@ -4,6 +4,7 @@ import "github.com/humpback/gounits/logger"
 import "github.com/humpback/humpback-center/api/request"
 import "github.com/humpback/humpback-center/api/response"
 import "github.com/humpback/humpback-center/cluster"
+import "github.com/humpback/humpback-center/cluster/types"
 
 import (
 	"net/http"
@@ -134,6 +135,122 @@ func getGroupEngine(c *Context) error {
 	return c.JSON(http.StatusOK, result)
 }
 
+func getGroupQuota(c *Context) error {
+
+	result := &response.ResponseResult{ResponseID: c.ID}
+	req, err := request.ResolveGroupQuotaRequest(c.Request())
+	if err != nil {
+		logger.ERROR("[#api#] %s resolve get group quota request faild, %s", c.ID, err.Error())
+		result.SetError(request.RequestInvalid, request.ErrRequestInvalid, err.Error())
+		return c.JSON(http.StatusBadRequest, result)
+	}
+
+	logger.INFO("[#api#] %s resolve get group quota request successed. %+v", c.ID, req)
+	quotaUsage, err := c.Controller.GetClusterGroupQuotaUsage(req.GroupID)
+	if err != nil {
+		logger.ERROR("[#api#] %s get group %s quota error: %s", c.ID, req.GroupID, err.Error())
+		result.SetError(request.RequestFailure, request.ErrRequestFailure, err.Error())
+		if err == cluster.ErrClusterGroupNotFound {
+			return c.JSON(http.StatusNotFound, result)
+		}
+		return c.JSON(http.StatusInternalServerError, result)
+	}
+
+	resp := response.NewGroupQuotaResponse(quotaUsage)
+	result.SetError(request.RequestSuccessed, request.ErrRequestSuccessed, "group quota response")
+	result.SetResponse(resp)
+	return c.JSON(http.StatusOK, result)
+}
+
+func getGroupOperation(c *Context) error {
+
+	result := &response.ResponseResult{ResponseID: c.ID}
+	req, err := request.ResolveGroupOperationRequest(c.Request())
+	if err != nil {
+		logger.ERROR("[#api#] %s resolve get operation request faild, %s", c.ID, err.Error())
+		result.SetError(request.RequestInvalid, request.ErrRequestInvalid, err.Error())
+		return c.JSON(http.StatusBadRequest, result)
+	}
+
+	logger.INFO("[#api#] %s resolve get operation request successed. %+v", c.ID, req)
+	operation, err := c.Controller.GetClusterOperation(req.OperationID)
+	if err != nil {
+		logger.ERROR("[#api#] %s get operation %s error: %s", c.ID, req.OperationID, err.Error())
+		result.SetError(request.RequestFailure, request.ErrRequestFailure, err.Error())
+		if err == cluster.ErrClusterOperationNotFound {
+			return c.JSON(http.StatusNotFound, result)
+		}
+		return c.JSON(http.StatusInternalServerError, result)
+	}
+
+	resp := response.NewGroupOperationResponse(operation)
+	result.SetError(request.RequestSuccessed, request.ErrRequestSuccessed, "operation response")
+	result.SetResponse(resp)
+	return c.JSON(http.StatusOK, result)
+}
+
+func getClusterMigrations(c *Context) error {
+
+	result := &response.ResponseResult{ResponseID: c.ID}
+	migrations := c.Controller.GetClusterMigrations()
+	resp := response.NewClusterMigrationsResponse(migrations)
+	result.SetError(request.RequestSuccessed, request.ErrRequestSuccessed, "cluster migrations response")
+	result.SetResponse(resp)
+	return c.JSON(http.StatusOK, result)
+}
+
+func putClusterStartMigration(c *Context) error {
+
+	result := &response.ResponseResult{ResponseID: c.ID}
+	req, err := request.ResolveClusterMigrationRequest(c.Request())
+	if err != nil {
+		logger.ERROR("[#api#] %s resolve start migration request faild, %s", c.ID, err.Error())
+		result.SetError(request.RequestInvalid, request.ErrRequestInvalid, err.Error())
+		return c.JSON(http.StatusBadRequest, result)
+	}
+
+	logger.INFO("[#api#] %s resolve start migration request successed. %+v", c.ID, req)
+	if err := c.Controller.StartClusterMigration(req.MetaID); err != nil {
+		logger.ERROR("[#api#] %s start migration %s error: %s", c.ID, req.MetaID, err.Error())
+		result.SetError(request.RequestFailure, request.ErrRequestFailure, err.Error())
+		if err == cluster.ErrClusterMigrationNotFound {
+			return c.JSON(http.StatusNotFound, result)
+		}
+		return c.JSON(http.StatusInternalServerError, result)
+	}
+
+	resp := response.NewClusterMigrationResponse(req.MetaID, "started.")
+	result.SetError(request.RequestSuccessed, request.ErrRequestSuccessed, "start migration response")
+	result.SetResponse(resp)
+	return c.JSON(http.StatusAccepted, result)
+}
+
+func deleteClusterCancelMigration(c *Context) error {
+
+	result := &response.ResponseResult{ResponseID: c.ID}
+	req, err := request.ResolveClusterMigrationRequest(c.Request())
+	if err != nil {
+		logger.ERROR("[#api#] %s resolve cancel migration request faild, %s", c.ID, err.Error())
+		result.SetError(request.RequestInvalid, request.ErrRequestInvalid, err.Error())
+		return c.JSON(http.StatusBadRequest, result)
+	}
+
+	logger.INFO("[#api#] %s resolve cancel migration request successed. %+v", c.ID, req)
+	if err := c.Controller.CancelClusterMigration(req.MetaID); err != nil {
+		logger.ERROR("[#api#] %s cancel migration %s error: %s", c.ID, req.MetaID, err.Error())
+		result.SetError(request.RequestFailure, request.ErrRequestFailure, err.Error())
+		if err == cluster.ErrClusterMigrationNotFound {
+			return c.JSON(http.StatusNotFound, result)
+		}
+		return c.JSON(http.StatusInternalServerError, result)
+	}
+
+	resp := response.NewClusterMigrationResponse(req.MetaID, "canceled.")
+	result.SetError(request.RequestSuccessed, request.ErrRequestSuccessed, "cancel migration response")
+	result.SetResponse(resp)
+	return c.JSON(http.StatusAccepted, result)
+}
+
 func postClusterEvent(c *Context) error {
 
 	result := &response.ResponseResult{ResponseID: c.ID}
@@ -181,7 +298,18 @@ func postGroupCreateContainers(c *Context) error {
 	}
 
 	logger.INFO("[#api#] %s resolve create containers request successed. %+v", c.ID, req)
-	metaid, createdContainers, err := c.Controller.CreateClusterContainers(req.GroupID, req.Instances, req.WebHooks, req.Placement, req.Config, req.Option)
+	var (
+		metaid            string
+		createdContainers *types.CreatedContainers
+		operation         *types.Operation
+	)
+
+	if req.Async {
+		operation, err = c.Controller.CreateClusterContainersAsync(req.GroupID, req.Instances, req.WebHooks, req.Placement, req.Config, req.Option)
+	} else {
+		metaid, createdContainers, err = c.Controller.CreateClusterContainers(req.GroupID, req.Instances, req.WebHooks, req.Placement, req.Config, req.Option)
+	}
+
 	if err != nil {
 		logger.ERROR("[#api#] %s create containers to group %s error: %s", c.ID, req.GroupID, err.Error())
 		result.SetError(request.RequestFailure, request.ErrRequestFailure, err.Error())
@@ -189,10 +317,21 @@ func postGroupCreateContainers(c *Context) error {
 			return c.JSON(http.StatusNotFound, result)
 		} else if err == cluster.ErrClusterCreateContainerNameConflict {
 			return c.JSON(http.StatusConflict, result)
+		} else if err == cluster.ErrClusterGroupQuotaExceeded {
+			return c.JSON(http.StatusForbidden, result)
+		} else if isRequestInvalid(err) {
+			return c.JSON(http.StatusBadRequest, result)
 		}
 		return c.JSON(http.StatusInternalServerError, result)
 	}
 
+	if operation != nil {
+		resp := response.NewGroupOperationResponse(operation)
+		result.SetError(request.RequestSuccessed, request.ErrRequestSuccessed, "create containers accepted")
+		result.SetResponse(resp)
+		return c.JSON(http.StatusAccepted, result)
+	}
+
 	resp := response.NewGroupCreateContainersResponse(req.GroupID, metaid, req.Instances, createdContainers)
 	result.SetError(request.RequestSuccessed, request.ErrRequestSuccessed, "create containers response")
 	result.SetResponse(resp)
@@ -210,22 +349,72 @@ func putGroupUpdateContainers(c *Context) error {
 	}
 
 	logger.INFO("[#api#] %s resolve update containers request successed. %+v", c.ID, req)
-	updatedContainers, err := c.Controller.UpdateClusterContainers(req.MetaID, req.Instances, req.WebHooks, req.Placement, req.Config, req.Option)
+	var (
+		updatedContainers *types.CreatedContainers
+		operation         *types.Operation
+	)
+
+	if req.Async {
+		operation, err = c.Controller.UpdateClusterContainersAsync(req.MetaID, req.Instances, req.WebHooks, req.Placement, req.Config, req.Option)
+	} else {
+		updatedContainers, err = c.Controller.UpdateClusterContainers(req.MetaID, req.Instances, req.WebHooks, req.Placement, req.Config, req.Option)
+	}
+
 	if err != nil {
 		logger.ERROR("[#api#] %s update containers to meta %s error: %s", c.ID, req.MetaID, err.Error())
 		result.SetError(request.RequestFailure, request.ErrRequestFailure, err.Error())
 		if err == cluster.ErrClusterMetaDataNotFound {
 			return c.JSON(http.StatusNotFound, result)
+		} else if err == cluster.ErrClusterGroupQuotaExceeded {
+			return c.JSON(http.StatusForbidden, result)
+		} else if isRequestInvalid(err) {
+			return c.JSON(http.StatusBadRequest, result)
 		}
 		return c.JSON(http.StatusInternalServerError, result)
 	}
 
+	if operation != nil {
+		resp := response.NewGroupOperationResponse(operation)
+		result.SetError(request.RequestSuccessed, request.ErrRequestSuccessed, "update containers accepted")
+		result.SetResponse(resp)
+		return c.JSON(http.StatusAccepted, result)
+	}
+
 	resp := response.NewGroupUpdateContainersResponse(req.MetaID, req.Instances, updatedContainers)
 	result.SetError(request.RequestSuccessed, request.ErrRequestSuccessed, "update containers response")
 	result.SetResponse(resp)
 	return c.JSON(http.StatusOK, result)
 }
 
+func postGroupPlanContainers(c *Context) error {
+
+	result := response.ResponseResult{ResponseID: c.ID}
+	req, err := request.ResolveGroupPlanContainersRequest(c.Request())
+	if err != nil {
+		logger.ERROR("[#api#] %s resolve plan containers request faild, %s", c.ID, err.Error())
+		result.SetError(request.RequestInvalid, request.ErrRequestInvalid, err.Error())
+		return c.JSON(http.StatusBadRequest, result)
+	}
+
+	logger.INFO("[#api#] %s resolve plan containers request successed. %+v", c.ID, req)
+	planContainers, err := c.Controller.PlanClusterContainers(req.GroupID, req.MetaID, req.Instances, req.Placement, req.Config)
+	if err != nil {
+		logger.ERROR("[#api#] %s plan containers to group %s meta %s error: %s", c.ID, req.GroupID, req.MetaID, err.Error())
+		result.SetError(request.RequestFailure, request.ErrRequestFailure, err.Error())
+		if err == cluster.ErrClusterMetaDataNotFound || err == cluster.ErrClusterGroupNotFound {
+			return c.JSON(http.StatusNotFound, result)
+		} else if err == cluster.ErrClusterContainersInstancesInvalid || isRequestInvalid(err) {
+			return c.JSON(http.StatusBadRequest, result)
+		}
+		return c.JSON(http.StatusInternalServerError, result)
+	}
+
+	resp := response.NewGroupPlanContainersResponse(planContainers)
+	result.SetError(request.RequestSuccessed, request.ErrRequestSuccessed, "plan containers response")
+	result.SetResponse(resp)
+	return c.JSON(http.StatusOK, result)
+}
+
 func putGroupOperateContainers(c *Context) error {
 
 	result := response.ResponseResult{ResponseID: c.ID}
@@ -297,6 +486,8 @@ func putGroupServerNodeLabels(c *Context) error {
 		result.SetError(request.RequestFailure, request.ErrRequestFailure, err.Error())
 		if err == cluster.ErrClusterServerNotFound {
 			return c.JSON(http.StatusNotFound, result)
+		} else if err == cluster.ErrClusterNodeLabelsInvalid {
+			return c.JSON(http.StatusBadRequest, result)
 		}
 		return c.JSON(http.StatusInternalServerError, result)
 	}
@@ -304,6 +495,58 @@ func putGroupServerNodeLabels(c *Context) error {
 	return c.JSON(http.StatusOK, result)
 }
 
+func putGroupServerAvailability(c *Context) error {
+
+	result := response.ResponseResult{ResponseID: c.ID}
+	req, err := request.ResolveServerAvailabilityRequest(c.Request())
+	if err != nil {
+		logger.ERROR("[#api#] %s resolve server availability request faild, %s", c.ID, err.Error())
+		result.SetError(request.RequestInvalid, request.ErrRequestInvalid, err.Error())
+		return c.JSON(http.StatusBadRequest, result)
+	}
+
+	logger.INFO("[#api#] %s resolve server availability request successed. %+v", c.ID, req)
+	err = c.Controller.SetClusterServerAvailability(req.Server, req.Availability)
+	if err != nil {
+		logger.ERROR("[#api#] %s server %s set availability error: %s", c.ID, req.Server, err.Error())
+		result.SetError(request.RequestFailure, request.ErrRequestFailure, err.Error())
+		if err == cluster.ErrClusterServerNotFound {
+			return c.JSON(http.StatusNotFound, result)
+		} else if err == cluster.ErrClusterAvailabilityInvalid {
+			return c.JSON(http.StatusBadRequest, result)
+		}
+		return c.JSON(http.StatusInternalServerError, result)
+	}
+	result.SetError(request.RequestSuccessed, request.ErrRequestSuccessed, "set availability response")
+	return c.JSON(http.StatusOK, result)
+}
+
+func putGroupQuota(c *Context) error {
+
+	result := response.ResponseResult{ResponseID: c.ID}
+	req, err := request.ResolveGroupSetQuotaRequest(c.Request())
+	if err != nil {
+		logger.ERROR("[#api#] %s resolve set group quota request faild, %s", c.ID, err.Error())
+		result.SetError(request.RequestInvalid, request.ErrRequestInvalid, err.Error())
+		return c.JSON(http.StatusBadRequest, result)
+	}
+
+	logger.INFO("[#api#] %s resolve set group quota request successed. %+v", c.ID, req)
+	err = c.Controller.SetClusterGroupQuota(req.GroupID, req.Quota)
+	if err != nil {
+		logger.ERROR("[#api#] %s group %s set quota error: %s", c.ID, req.GroupID, err.Error())
+		result.SetError(request.RequestFailure, request.ErrRequestFailure, err.Error())
+		if err == cluster.ErrClusterGroupNotFound {
+			return c.JSON(http.StatusNotFound, result)
+		} else if err == cluster.ErrClusterGroupQuotaInvalid {
+			return c.JSON(http.StatusBadRequest, result)
+		}
+		return c.JSON(http.StatusInternalServerError, result)
+	}
+	result.SetError(request.RequestSuccessed, request.ErrRequestSuccessed, "set group quota response")
+	return c.JSON(http.StatusOK, result)
+}
+
 func putGroupUpgradeContainers(c *Context) error {
 
 	result := response.ResponseResult{ResponseID: c.ID}
@@ -315,22 +558,95 @@ func putGroupUpgradeContainers(c *Context) error {
 	}
 
 	logger.INFO("[#api#] %s resolve upgrade containers request successed. %+v", c.ID, req)
-	upgradeContainers, err := c.Controller.UpgradeContainers(req.MetaID, req.ImageTag)
+	var (
+		upgradeContainers *types.UpgradeContainers
+		operation         *types.Operation
+	)
+
+	if req.Async {
+		operation, err = c.Controller.UpgradeContainersAsync(req.MetaID, req.ImageTag, req.Rolling)
+	} else {
+		upgradeContainers, err = c.Controller.UpgradeContainers(req.MetaID, req.ImageTag, req.Rolling)
+	}
+
 	if err != nil {
 		logger.ERROR("[#api#] %s upgrade containers to meta %s error: %s", c.ID, req.MetaID, err.Error())
 		result.SetError(request.RequestFailure, request.ErrRequestFailure, err.Error())
 		if err == cluster.ErrClusterMetaDataNotFound || err == cluster.ErrClusterGroupNotFound {
 			return c.JSON(http.StatusNotFound, result)
+		} else if err == cluster.ErrClusterRollingUpgradeInvalid {
+			return c.JSON(http.StatusBadRequest, result)
 		}
 		return c.JSON(http.StatusInternalServerError, result)
 	}
 
+	if operation != nil {
+		resp := response.NewGroupOperationResponse(operation)
+		result.SetError(request.RequestSuccessed, request.ErrRequestSuccessed, "upgrade containers accepted")
+		result.SetResponse(resp)
+		return c.JSON(http.StatusAccepted, result)
+	}
+
 	resp := response.NewGroupUpgradeContainersResponse(req.MetaID, "upgrade containers", upgradeContainers)
 	result.SetError(request.RequestSuccessed, request.ErrRequestSuccessed, "upgrade containers response")
 	result.SetResponse(resp)
 	return c.JSON(http.StatusOK, result)
 }
 
+func putGroupRebalanceContainers(c *Context) error {
+
+	result := response.ResponseResult{ResponseID: c.ID}
+	req, err := request.ResolveGroupRebalanceContainersRequest(c.Request())
+	if err != nil {
+		logger.ERROR("[#api#] %s resolve rebalance containers request faild, %s", c.ID, err.Error())
+		result.SetError(request.RequestInvalid, request.ErrRequestInvalid, err.Error())
+		return c.JSON(http.StatusBadRequest, result)
+	}
+
+	logger.INFO("[#api#] %s resolve rebalance containers request successed. %+v", c.ID, req)
+	rebalanceContainers, err := c.Controller.RebalanceContainers(req.MetaID, req.PauseDuration)
+	if err != nil {
+		logger.ERROR("[#api#] %s rebalance containers to meta %s error: %s", c.ID, req.MetaID, err.Error())
+		result.SetError(request.RequestFailure, request.ErrRequestFailure, err.Error())
+		if err == cluster.ErrClusterMetaDataNotFound || err == cluster.ErrClusterGroupNotFound {
+			return c.JSON(http.StatusNotFound, result)
+		}
+		return c.JSON(http.StatusInternalServerError, result)
+	}
+
+	resp := response.NewGroupRebalanceContainersResponse([]*types.RebalanceContainers{rebalanceContainers})
+	result.SetError(request.RequestSuccessed, request.ErrRequestSuccessed, "rebalance containers response")
+	result.SetResponse(resp)
+	return c.JSON(http.StatusAccepted, result)
+}
+
+func putGroupRebalanceGroupContainers(c *Context) error {
+
+	result := response.ResponseResult{ResponseID: c.ID}
+	req, err := request.ResolveGroupRebalanceGroupContainersRequest(c.Request())
+	if err != nil {
+		logger.ERROR("[#api#] %s resolve rebalance group containers request faild, %s", c.ID, err.Error())
+		result.SetError(request.RequestInvalid, request.ErrRequestInvalid, err.Error())
+		return c.JSON(http.StatusBadRequest, result)
+	}
+
+	logger.INFO("[#api#] %s resolve rebalance group containers request successed. %+v", c.ID, req)
+	rebalanceContainers, err := c.Controller.RebalanceGroupContainers(req.GroupID, req.PauseDuration)
+	if err != nil {
+		logger.ERROR("[#api#] %s rebalance containers to group %s error: %s", c.ID, req.GroupID, err.Error())
+		result.SetError(request.RequestFailure, request.ErrRequestFailure, err.Error())
+		if err == cluster.ErrClusterGroupNotFound {
+			return c.JSON(http.StatusNotFound, result)
+		}
+		return c.JSON(http.StatusInternalServerError, result)
+	}
+
+	resp := response.NewGroupRebalanceContainersResponse(rebalanceContainers)
+	result.SetError(request.RequestSuccessed, request.ErrRequestSuccessed, "rebalance group containers response")
+	result.SetResponse(resp)
+	return c.JSON(http.StatusAccepted, result)
+}
+
 func deleteGroupRemoveContainersOfMetaName(c *Context) error {
 
 	result := response.ResponseResult{ResponseID: c.ID}
@@ -369,7 +685,17 @@ func deleteGroupRemoveContainers(c *Context) error {
 	}
 
 	logger.INFO("[#api#] %s resolve remove containers request successed. %+v", c.ID, req)
-	removedContainers, err := c.Controller.RemoveContainers(req.MetaID)
+	var (
+		removedContainers *types.RemovedContainers
+		operation         *types.Operation
+	)
+
+	if req.Async {
+		operation, err = c.Controller.RemoveContainersAsync(req.MetaID)
+	} else {
+		removedContainers, err = c.Controller.RemoveContainers(req.MetaID)
+	}
+
 	if err != nil {
 		logger.ERROR("[#api#] %s remove containers to meta %s error: %s", c.ID, req.MetaID, err.Error())
 		result.SetError(request.RequestFailure, request.ErrRequestFailure, err.Error())
@@ -379,6 +705,13 @@ func deleteGroupRemoveContainers(c *Context) error {
 		return c.JSON(http.StatusInternalServerError, result)
 	}
 
+	if operation != nil {
+		resp := response.NewGroupOperationResponse(operation)
+		result.SetError(request.RequestSuccessed, request.ErrRequestSuccessed, "remove containers accepted")
+		result.SetResponse(resp)
+		return c.JSON(http.StatusAccepted, result)
+	}
+
 	resp := response.NewGroupRemoveContainersResponse(req.MetaID, removedContainers)
 	result.SetError(request.RequestSuccessed, request.ErrRequestSuccessed, "remove containers response")
 	result.SetResponse(resp)
@@ -10,7 +10,9 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 )
 
 /*
@@ -107,6 +109,52 @@ func ResolveGroupEnginesRequest(r *http.Request) (*GroupEnginesRequest, error) {
 	}, nil
 }
 
+/*
+GroupQuotaRequest is exported
+Method:  GET
+Route:   /v1/groups/{groupid}/quota
+*/
+type GroupQuotaRequest struct {
+	GroupID string `json:"GroupId"`
+}
+
+// ResolveGroupQuotaRequest is exported
+func ResolveGroupQuotaRequest(r *http.Request) (*GroupQuotaRequest, error) {
+
+	vars := mux.Vars(r)
+	groupid := strings.TrimSpace(vars["groupid"])
+	if len(strings.TrimSpace(groupid)) == 0 {
+		return nil, fmt.Errorf("groupid invalid, can not be empty")
+	}
+
+	return &GroupQuotaRequest{
+		GroupID: groupid,
+	}, nil
+}
+
+/*
+GroupOperationRequest is exported
+Method:  GET
+Route:   /v1/groups/operations/{operationid}
+*/
+type GroupOperationRequest struct {
+	OperationID string `json:"OperationId"`
+}
+
+// ResolveGroupOperationRequest is exported
+func ResolveGroupOperationRequest(r *http.Request) (*GroupOperationRequest, error) {
+
+	vars := mux.Vars(r)
+	operationid := strings.TrimSpace(vars["operationid"])
+	if len(operationid) == 0 {
+		return nil, fmt.Errorf("operationid invalid, can not be empty")
+	}
+
+	return &GroupOperationRequest{
+		OperationID: operationid,
+	}, nil
+}
+
 /*
 GroupEngineRequest is exported
 Method:  GET
@@ -199,6 +247,7 @@ func ResolveGroupEventRequest(r *http.Request) (*GroupEventRequest, error) {
 GroupCreateContainersRequest is exported
 Method:  POST
 Route:   /v1/groups/collections
+query async=true returns the queued operation without waiting for it.
 */
 type GroupCreateContainersRequest struct {
 	GroupID   string             `json:"GroupId"`
@@ -207,6 +256,7 @@ type GroupCreateContainersRequest struct {
 	WebHooks  types.WebHooks     `json:"WebHooks"`
 	Config    models.Container   `json:"Config"`
 	Option    types.CreateOption `json:"Option"`
+	Async     bool               `json:"-"`
 }
 
 // ResolveGroupCreateContainersRequest is exported
@@ -239,6 +289,10 @@ func ResolveGroupCreateContainersRequest(r *http.Request) (*GroupCreateContainer
 	if len(strings.TrimSpace(request.Config.Name)) == 0 {
 		return nil, fmt.Errorf("create containers name can not be empty")
 	}
+
+	if request.Async, err = resolveAsync(r); err != nil {
+		return nil, err
+	}
 	return request, nil
 }
 
@@ -246,6 +300,7 @@ func ResolveGroupCreateContainersRequest(r *http.Request) (*GroupCreateContainer
 GroupUpdateContainersRequest is exported
 Method:  PUT
 Route:   /v1/groups/collections
+query async=true returns the queued operation without waiting for it.
 */
 type GroupUpdateContainersRequest struct {
 	MetaID    string             `json:"MetaId"`
@@ -254,6 +309,7 @@ type GroupUpdateContainersRequest struct {
 	WebHooks  types.WebHooks     `json:"WebHooks"`
 	Config    models.Container   `json:"Config"`
 	Option    types.UpdateOption `json:"Option"`
+	Async     bool               `json:"-"`
 }
 
 // ResolveGroupUpdateContainersRequest is exported
@@ -282,6 +338,57 @@ func ResolveGroupUpdateContainersRequest(r *http.Request) (*GroupUpdateContainer
 	if request.Instances < 0 {
 		return nil, fmt.Errorf("set containers instances invalid, should be larger or equal than 0")
 	}
+
+	if request.Async, err = resolveAsync(r); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+/*
+GroupPlanContainersRequest is exported
+Method:  POST
+Route:   /v1/groups/collections/plan
+body is the same as create containers request or update containers request,
+MetaId is empty so plan create containers, otherwise plan update containers.
+*/
+type GroupPlanContainersRequest struct {
+	GroupID   string           `json:"GroupId"`
+	MetaID    string           `json:"MetaId"`
+	Instances int              `json:"Instances"`
+	Placement types.Placement  `json:"Placement"`
+	Config    models.Container `json:"Config"`
+}
+
+// ResolveGroupPlanContainersRequest is exported
+func ResolveGroupPlanContainersRequest(r *http.Request) (*GroupPlanContainersRequest, error) {
+
+	buf, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	request := &GroupPlanContainersRequest{}
+	if err := json.NewDecoder(bytes.NewReader(buf)).Decode(request); err != nil {
+		return nil, err
+	}
+
+	request.MetaID = strings.TrimSpace(request.MetaID)
+	if len(request.MetaID) == 0 {
+		if len(strings.TrimSpace(request.GroupID)) == 0 {
+			return nil, fmt.Errorf("plan containers groupid or metaid invalid, can not be empty")
+		}
+
+		if request.Instances <= 0 {
+			return nil, fmt.Errorf("plan containers instances invalid, should be larger than 0")
+		}
+
+		if len(strings.TrimSpace(request.Config.Name)) == 0 {
+			return nil, fmt.Errorf("plan containers name can not be empty")
+		}
+	} else if request.Instances < 0 {
+		return nil, fmt.Errorf("plan containers instances invalid, should be larger or equal than 0")
+	}
 	return request, nil
 }
 
@@ -347,10 +454,14 @@ func ResolveGroupOperateContainerRequest(r *http.Request) (*GroupOperateContaine
 GroupUpgradeContainersRequest is exported
 Method:  PUT
 Route:   /v1/groups/collections/upgrade
+query async=true returns the queued operation without waiting for it.
+Rolling is optional, containers are upgraded batch by batch when it is set.
 */
 type GroupUpgradeContainersRequest struct {
-	MetaID   string `json:"MetaId"`
-	ImageTag string `json:"ImageTag"`
+	MetaID   string                `json:"MetaId"`
+	ImageTag string                `json:"ImageTag"`
+	Rolling  *types.RollingUpgrade `json:"Rolling"`
+	Async    bool                  `json:"-"`
 }
 
 // ResolveGroupUpgradeContainersRequest is exported
@@ -369,9 +480,119 @@ func ResolveGroupUpgradeContainersRequest(r *http.Request) (*GroupUpgradeContain
 	if len(strings.TrimSpace(request.MetaID)) == 0 {
 		return nil, fmt.Errorf("upgrade containers metaid invalid, can not be empty")
 	}
+
+	if request.Async, err = resolveAsync(r); err != nil {
+		return nil, err
+	}
 	return request, nil
 }
 
+/*
+GroupRebalanceContainersRequest is exported
+Method:  PUT
+Route:   /v1/groups/collections/{metaid}/rebalance
+body is optional, Pause is the interval between two moves, like '30s', empty is default pause.
+*/
+type GroupRebalanceContainersRequest struct {
+	MetaID        string        `json:"MetaId"`
+	Pause         string        `json:"Pause"`
+	PauseDuration time.Duration `json:"-"`
+}
+
+// ResolveGroupRebalanceContainersRequest is exported
+func ResolveGroupRebalanceContainersRequest(r *http.Request) (*GroupRebalanceContainersRequest, error) {
+
+	buf, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	request := &GroupRebalanceContainersRequest{}
+	if len(bytes.TrimSpace(buf)) > 0 {
+		if err := json.NewDecoder(bytes.NewReader(buf)).Decode(request); err != nil {
+			return nil, err
+		}
+	}
+
+	vars := mux.Vars(r)
+	request.MetaID = strings.TrimSpace(vars["metaid"])
+	if len(request.MetaID) == 0 {
+		return nil, fmt.Errorf("rebalance containers metaid invalid, can not be empty")
+	}
+
+	if request.PauseDuration, err = resolveRebalancePause(request.Pause); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+/*
+GroupRebalanceGroupContainersRequest is exported
+Method:  PUT
+Route:   /v1/groups/{groupid}/collections/rebalance
+body is optional, Pause is the interval between two moves, like '30s', empty is default pause.
+*/
+type GroupRebalanceGroupContainersRequest struct {
+	GroupID       string        `json:"GroupId"`
+	Pause         string        `json:"Pause"`
+	PauseDuration time.Duration `json:"-"`
+}
+
+// ResolveGroupRebalanceGroupContainersRequest is exported
+func ResolveGroupRebalanceGroupContainersRequest(r *http.Request) (*GroupRebalanceGroupContainersRequest, error) {
+
+	buf, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	request := &GroupRebalanceGroupContainersRequest{}
+	if len(bytes.TrimSpace(buf)) > 0 {
+		if err := json.NewDecoder(bytes.NewReader(buf)).Decode(request); err != nil {
+			return nil, err
+		}
+	}
+
+	vars := mux.Vars(r)
+	request.GroupID = strings.TrimSpace(vars["groupid"])
+	if len(request.GroupID) == 0 {
+		return nil, fmt.Errorf("rebalance containers groupid invalid, can not be empty")
+	}
+
+	if request.PauseDuration, err = resolveRebalancePause(request.Pause); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+func resolveRebalancePause(pause string) (time.Duration, error) {
+
+	pause = strings.TrimSpace(pause)
+	if len(pause) == 0 {
+		return 0, nil
+	}
+
+	duration, err := time.ParseDuration(pause)
+	if err != nil || duration < 0 {
+		return 0, fmt.Errorf("rebalance containers pause %s invalid", pause)
+	}
+	return duration, nil
+}
+
+func resolveAsync(r *http.Request) (bool, error) {
+
+	value := strings.TrimSpace(r.URL.Query().Get("async"))
+	if len(value) == 0 {
+		return false, nil
+	}
+
+	async, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("async %s invalid", value)
+	}
+	return async, nil
+}
+
 /*
 GroupRemoveContainersOfMetaNameRequest is exported
 Method:  DELETE
@@ -405,9 +626,11 @@ func ResolveGroupRemoveContainersOfMetaNameRequest(r *http.Request) (*GroupRemov
 GroupRemoveContainersRequest is exported
 Method:  DELETE
 Route:   /v1/groups/collections/{metaid}
+query async=true returns the queued operation without waiting for it.
 */
 type GroupRemoveContainersRequest struct {
 	MetaID string `json:"MetaId"`
+	Async  bool   `json:"-"`
 }
 
 // ResolveGroupRemoveContainersRequest is exported
@@ -418,8 +641,15 @@ func ResolveGroupRemoveContainersRequest(r *http.Request) (*GroupRemoveContainer
 	if len(strings.TrimSpace(metaid)) == 0 {
 		return nil, fmt.Errorf("remove containers metaid invalid, can not be empty")
 	}
+
+	async, err := resolveAsync(r)
+	if err != nil {
+		return nil, err
+	}
+
 	request := &GroupRemoveContainersRequest{
 		MetaID: metaid,
+		Async:  async,
 	}
 	return request, nil
 }
@@ -472,3 +702,92 @@ func ResolveServerNodeLabelsRequest(r *http.Request) (*ServerNodeLabelsRequest,
 	}
 	return request, nil
 }
+
+/*
+ServerAvailabilityRequest is exported
+Method:  PUT
+Route:   /v1/groups/availability
+Availability is Active, Pause or Drain.
+*/
+type ServerAvailabilityRequest struct {
+	Server       string `json:"Server"`
+	Availability string `json:"Availability"`
+}
+
+// ResolveServerAvailabilityRequest is exported
+func ResolveServerAvailabilityRequest(r *http.Request) (*ServerAvailabilityRequest, error) {
+
+	buf, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	request := &ServerAvailabilityRequest{}
+	if err := json.NewDecoder(bytes.NewReader(buf)).Decode(request); err != nil {
+		return nil, err
+	}
+
+	if len(strings.TrimSpace(request.Server)) == 0 {
+		return nil, fmt.Errorf("server invalid, can not be empty")
+	}
+	return request, nil
+}
+
+/*
+GroupSetQuotaRequest is exported
+Method:  PUT
+Route:   /v1/groups/quota
+Quota with all zero values removes the group quota.
+*/
+type GroupSetQuotaRequest struct {
+	GroupID string           `json:"GroupId"`
+	Quota   types.GroupQuota `json:"Quota"`
+}
+
+// ResolveGroupSetQuotaRequest is exported
+func ResolveGroupSetQuotaRequest(r *http.Request) (*GroupSetQuotaRequest, error) {
+
+	buf, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	request := &GroupSetQuotaRequest{}
+	if err := json.NewDecoder(bytes.NewReader(buf)).Decode(request); err != nil {
+		return nil, err
+	}
+
+	request.GroupID = strings.TrimSpace(request.GroupID)
+	if len(request.GroupID) == 0 {
+		return nil, fmt.Errorf("set group quota groupid invalid, can not be empty")
+	}
+
+	if request.Quota.MaxInstances < 0 || request.Quota.MaxCPUShares < 0 || request.Quota.MaxMemory < 0 {
+		return nil, fmt.Errorf("set group quota invalid, should be larger or equal than 0")
+	}
+	return request, nil
+}
+
+/*
+ClusterMigrationRequest is exported
+Method:  PUT | DELETE
+Route1:  /v1/cluster/migrations/{metaid}/start
+Route2:  /v1/cluster/migrations/{metaid}
+*/
+type ClusterMigrationRequest struct {
+	MetaID string `json:"MetaId"`
+}
+
+// ResolveClusterMigrationRequest is exported
+func ResolveClusterMigrationRequest(r *http.Request) (*ClusterMigrationRequest, error) {
+
+	vars := mux.Vars(r)
+	metaid := strings.TrimSpace(vars["metaid"])
+	if len(metaid) == 0 {
+		return nil, fmt.Errorf("migration metaid invalid, can not be empty")
+	}
+
+	return &ClusterMigrationRequest{
+		MetaID: metaid,
+	}, nil
+}
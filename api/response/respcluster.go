@@ -43,14 +43,16 @@ func NewGroupContainersResponse(container *types.GroupContainer) *GroupContainer
 
 // ContainersMetaBase is exported
 type ContainersMetaBase struct {
-	GroupID       string          `json:"GroupId"`
-	MetaID        string          `json:"MetaId"`
-	IsRemoveDelay bool            `json:"IsRemoveDelay"`
-	IsRecovery    bool            `json:"IsRecovery"`
-	Instances     int             `json:"Instances"`
-	Placement     types.Placement `json:"Placement"`
-	WebHooks      types.WebHooks  `json:"WebHooks"`
-	ImageTag      string          `json:"ImageTag"`
+	GroupID       string              `json:"GroupId"`
+	MetaID        string              `json:"MetaId"`
+	IsRemoveDelay bool                `json:"IsRemoveDelay"`
+	IsRecovery    bool                `json:"IsRecovery"`
+	Priority      int                 `json:"Priority"`
+	MigratePolicy types.MigratePolicy `json:"MigratePolicy"`
+	Instances     int                 `json:"Instances"`
+	Placement     types.Placement     `json:"Placement"`
+	WebHooks      types.WebHooks      `json:"WebHooks"`
+	ImageTag      string              `json:"ImageTag"`
 	models.Container
 	CreateAt     int64 `json:"CreateAt"`
 	LastUpdateAt int64 `json:"LastUpdateAt"`
@@ -105,6 +107,8 @@ func NewGroupContainersMetaBaseResponse(metaBase *cluster.MetaBase) *GroupContai
 		MetaID:        metaBase.MetaID,
 		IsRemoveDelay: metaBase.IsRemoveDelay,
 		IsRecovery:    metaBase.IsRecovery,
+		Priority:      metaBase.Priority,
+		MigratePolicy: metaBase.MigratePolicy,
 		Instances:     metaBase.Instances,
 		Placement:     metaBase.Placement,
 		WebHooks:      metaBase.WebHooks,
@@ -138,6 +142,23 @@ func NewGroupEnginesResponse(groupid string, engines []*cluster.Engine) *GroupEn
 	}
 }
 
+/*
+GroupQuotaResponse is exported
+Method:  GET
+Route:   /v1/groups/{groupid}/quota
+*/
+type GroupQuotaResponse struct {
+	QuotaUsage *types.GroupQuotaUsage `json:"QuotaUsage"`
+}
+
+// NewGroupQuotaResponse is exported
+func NewGroupQuotaResponse(quotaUsage *types.GroupQuotaUsage) *GroupQuotaResponse {
+
+	return &GroupQuotaResponse{
+		QuotaUsage: quotaUsage,
+	}
+}
+
 /*
 GroupEngineResponse is exported
 Method:  GET
@@ -243,6 +264,41 @@ func NewGroupUpdateContainersResponse(metaid string, instances int, containers *
 	}
 }
 
+/*
+GroupPlanContainersResponse is exported
+Method:  POST
+Route:   /v1/groups/collections/plan
+*/
+type GroupPlanContainersResponse struct {
+	Plan *types.PlanContainers `json:"Plan"`
+}
+
+// NewGroupPlanContainersResponse is exported
+func NewGroupPlanContainersResponse(plan *types.PlanContainers) *GroupPlanContainersResponse {
+
+	return &GroupPlanContainersResponse{
+		Plan: plan,
+	}
+}
+
+/*
+GroupRebalanceContainersResponse is exported
+Method:  PUT
+Route1:  /v1/groups/collections/{metaid}/rebalance
+Route2:  /v1/groups/{groupid}/collections/rebalance
+*/
+type GroupRebalanceContainersResponse struct {
+	Rebalances []*types.RebalanceContainers `json:"Rebalances"`
+}
+
+// NewGroupRebalanceContainersResponse is exported
+func NewGroupRebalanceContainersResponse(rebalances []*types.RebalanceContainers) *GroupRebalanceContainersResponse {
+
+	return &GroupRebalanceContainersResponse{
+		Rebalances: rebalances,
+	}
+}
+
 /*
 GroupOperateContainersResponse is exported
 Method:  PUT
@@ -305,3 +361,58 @@ func NewGroupRemoveContainersResponse(metaid string, containers *types.RemovedCo
 		Containers: containers,
 	}
 }
+
+/*
+GroupOperationResponse is exported
+Method:  GET
+Route:   /v1/groups/operations/{operationid}
+also the accepted response of create, update, upgrade and remove containers with query async=true.
+*/
+type GroupOperationResponse struct {
+	Operation *types.Operation `json:"Operation"`
+}
+
+// NewGroupOperationResponse is exported
+func NewGroupOperationResponse(operation *types.Operation) *GroupOperationResponse {
+
+	return &GroupOperationResponse{
+		Operation: operation,
+	}
+}
+
+/*
+ClusterMigrationsResponse is exported
+Method:  GET
+Route:   /v1/cluster/migrations
+*/
+type ClusterMigrationsResponse struct {
+	Migrations []*types.Migration `json:"Migrations"`
+}
+
+// NewClusterMigrationsResponse is exported
+func NewClusterMigrationsResponse(migrations []*types.Migration) *ClusterMigrationsResponse {
+
+	return &ClusterMigrationsResponse{
+		Migrations: migrations,
+	}
+}
+
+/*
+ClusterMigrationResponse is exported
+Method:  PUT | DELETE
+Route1:  /v1/cluster/migrations/{metaid}/start
+Route2:  /v1/cluster/migrations/{metaid}
+*/
+type ClusterMigrationResponse struct {
+	MetaID  string `json:"MetaId"`
+	Message string `json:"Message"`
+}
+
+// NewClusterMigrationResponse is exported
+func NewClusterMigrationResponse(metaid string, message string) *ClusterMigrationResponse {
+
+	return &ClusterMigrationResponse{
+		MetaID:  metaid,
+		Message: message,
+	}
+}
@@ -15,26 +15,36 @@ var routes = map[string]map[string]handler{
 		"/v1/configuration":                    getConfiguration,
 		"/v1/groups/{groupid}/collections":     getGroupAllContainers,
 		"/v1/groups/{groupid}/engines":         getGroupEngines,
+		"/v1/groups/{groupid}/quota":           getGroupQuota,
 		"/v1/groups/collections/{metaid}":      getGroupContainers,
 		"/v1/groups/collections/{metaid}/base": getGroupContainersMetaBase,
 		"/v1/groups/engines/{server}":          getGroupEngine,
+		"/v1/groups/operations/{operationid}":  getGroupOperation,
+		"/v1/cluster/migrations":               getClusterMigrations,
 	},
 	"POST": {
-		"/v1/groups/event":       postGroupEvent,
-		"/v1/cluster/event":      postClusterEvent,
-		"/v1/groups/collections": postGroupCreateContainers,
+		"/v1/groups/event":            postGroupEvent,
+		"/v1/cluster/event":           postClusterEvent,
+		"/v1/groups/collections":      postGroupCreateContainers,
+		"/v1/groups/collections/plan": postGroupPlanContainers,
 	},
 	"PUT": {
-		"/v1/groups/collections":         putGroupUpdateContainers,
-		"/v1/groups/collections/upgrade": putGroupUpgradeContainers,
-		"/v1/groups/collections/action":  putGroupOperateContainers,
-		"/v1/groups/container/action":    putGroupOperateContainer,
-		"/v1/groups/nodelabels":          putGroupServerNodeLabels,
+		"/v1/groups/collections":                     putGroupUpdateContainers,
+		"/v1/groups/collections/upgrade":             putGroupUpgradeContainers,
+		"/v1/groups/collections/action":              putGroupOperateContainers,
+		"/v1/groups/container/action":                putGroupOperateContainer,
+		"/v1/groups/nodelabels":                      putGroupServerNodeLabels,
+		"/v1/groups/availability":                    putGroupServerAvailability,
+		"/v1/groups/quota":                           putGroupQuota,
+		"/v1/groups/collections/{metaid}/rebalance":  putGroupRebalanceContainers,
+		"/v1/groups/{groupid}/collections/rebalance": putGroupRebalanceGroupContainers,
+		"/v1/cluster/migrations/{metaid}/start":      putClusterStartMigration,
 	},
 	"DELETE": {
 		"/v1/groups/{groupid}/collections/{metaname}": deleteGroupRemoveContainersOfMetaName,
 		"/v1/groups/collections/{metaid}":             deleteGroupRemoveContainers,
 		"/v1/groups/container/{containerid}":          deleteGroupRemoveContainer,
+		"/v1/cluster/migrations/{metaid}":             deleteClusterCancelMigration,
 	},
 }
 
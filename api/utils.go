@@ -1,5 +1,7 @@
 package api
 
+import "github.com/humpback/humpback-center/cluster"
+
 import "net/http"
 
 func httpError(w http.ResponseWriter, err string, code int) {
@@ -11,3 +13,10 @@ func writeCorsHeaders(w http.ResponseWriter, r *http.Request) {
 	w.Header().Add("Access-Control-Allow-Headers", "Origin, X-Requested-With, Content-Type, Accept")
 	w.Header().Add("Access-Control-Allow-Methods", "GET, POST, DELETE, PUT, OPTIONS, HEAD")
 }
+
+// isRequestInvalid returns true if err is an invalid placement or option of create or update containers request.
+func isRequestInvalid(err error) bool {
+	return err == cluster.ErrClusterStrategyInvalid || err == cluster.ErrClusterPortRangeInvalid ||
+		err == cluster.ErrClusterReducePolicyInvalid || err == cluster.ErrClusterAffinitiesInvalid ||
+		err == cluster.ErrClusterMigratePolicyInvalid
+}
@@ -0,0 +1,264 @@
+package cluster
+
+import "github.com/humpback/humpback-center/cluster/types"
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	// AffinityType is exported
+	AffinityType = "affinity"
+	// AntiAffinityType is exported
+	AntiAffinityType = "antiaffinity"
+	// AffinityHardMode is exported
+	AffinityHardMode = "hard"
+	// AffinitySoftMode is exported
+	AffinitySoftMode = "soft"
+)
+
+// Affinity defines an inter-meta affinity rule.
+// other metas are selected in the same group by meta name or meta label.
+type Affinity struct {
+	anti       bool
+	hard       bool
+	metaName   string
+	labelKey   string
+	labelValue string
+	hasValue   bool
+}
+
+// ParseAffinities parses list of placement affinities.
+func ParseAffinities(affinities []types.Affinity) ([]Affinity, error) {
+
+	rules := []Affinity{}
+	for _, a := range affinities {
+		rule := Affinity{}
+		switch strings.ToLower(strings.TrimSpace(a.Type)) {
+		case AffinityType:
+			rule.anti = false
+		case AntiAffinityType:
+			rule.anti = true
+		default:
+			return nil, fmt.Errorf("affinity type '%s' is invalid, expected %s or %s", a.Type, AffinityType, AntiAffinityType)
+		}
+
+		switch strings.ToLower(strings.TrimSpace(a.Mode)) {
+		case "", AffinityHardMode:
+			rule.hard = true
+		case AffinitySoftMode:
+			rule.hard = false
+		default:
+			return nil, fmt.Errorf("affinity mode '%s' is invalid, expected %s or %s", a.Mode, AffinityHardMode, AffinitySoftMode)
+		}
+
+		rule.metaName = strings.TrimSpace(a.MetaName)
+		metaLabel := strings.TrimSpace(a.MetaLabel)
+		if rule.metaName == "" && metaLabel == "" {
+			return nil, fmt.Errorf("affinity MetaName or MetaLabel is required")
+		}
+
+		if metaLabel != "" {
+			parts := strings.SplitN(metaLabel, "=", 2)
+			rule.labelKey = strings.TrimSpace(parts[0])
+			if rule.labelKey == "" {
+				return nil, fmt.Errorf("affinity meta label '%s' is invalid", metaLabel)
+			}
+			if len(parts) == 2 {
+				rule.labelValue = strings.TrimSpace(parts[1])
+				rule.hasValue = true
+			}
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// MatchMeta returns true if the metaData is selected by the Affinity.
+// both meta name and meta label must match when they are set.
+func (affinity *Affinity) MatchMeta(metaData *MetaData) bool {
+
+	if affinity.metaName != "" && !strings.EqualFold(affinity.metaName, metaData.Config.Name) {
+		return false
+	}
+
+	if affinity.labelKey != "" {
+		// label itself is case sensitive
+		value, ret := metaData.Config.Labels[affinity.labelKey]
+		if !ret {
+			return false
+		}
+		if affinity.hasValue && value != affinity.labelValue {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns the Affinity readable description, like 'hard antiaffinity MetaName=db'.
+func (affinity *Affinity) String() string {
+
+	values := []string{}
+	if affinity.hard {
+		values = append(values, AffinityHardMode)
+	} else {
+		values = append(values, AffinitySoftMode)
+	}
+
+	if affinity.anti {
+		values = append(values, AntiAffinityType)
+	} else {
+		values = append(values, AffinityType)
+	}
+
+	if affinity.metaName != "" {
+		values = append(values, "MetaName="+affinity.metaName)
+	}
+
+	if affinity.labelKey != "" {
+		label := affinity.labelKey
+		if affinity.hasValue {
+			label = label + "=" + affinity.labelValue
+		}
+		values = append(values, "MetaLabel="+label)
+	}
+	return strings.Join(values, " ")
+}
+
+// Count returns the number of containers of the selected metas on engine.
+func (affinity *Affinity) Count(engine *Engine, metaids []string) int {
+
+	count := 0
+	for _, metaid := range metaids {
+		count = count + engine.ScheduledContainers(metaid)
+	}
+	return count
+}
+
+type affinityEngine struct {
+	engine *Engine
+	score  int
+}
+
+type affinityEngines []*affinityEngine
+
+func (engines affinityEngines) Len() int {
+
+	return len(engines)
+}
+
+func (engines affinityEngines) Swap(i, j int) {
+
+	engines[i], engines[j] = engines[j], engines[i]
+}
+
+func (engines affinityEngines) Less(i, j int) bool {
+
+	return engines[i].score > engines[j].score
+}
+
+// AffinityEngines returns engines matching the hard affinities, ordered by soft affinities score.
+// groupMetaData is the meta group all metaData, the meta itself is excluded.
+// hard antiaffinities of other group metas which select metaData also apply, an engine is never chosen against their rules.
+// engines order is preserved for equal scores, so the first engine of the result is still the best weighted engine.
+func AffinityEngines(affinities []Affinity, metaData *MetaData, groupMetaData []*MetaData, engines []*Engine) []*Engine {
+
+	return selectAffinityEngines(affinities, metaData, groupMetaData, engines, nil)
+}
+
+// selectAffinityEngines returns AffinityEngines result, reject is called with the first hard affinity which an engine doesn't match.
+func selectAffinityEngines(affinities []Affinity, metaData *MetaData, groupMetaData []*MetaData, engines []*Engine, reject func(engine *Engine, affinity *Affinity)) []*Engine {
+
+	reverseAffinities, reverseMetaIds := selectReverseAffinities(metaData, groupMetaData)
+	if (len(affinities) == 0 && len(reverseAffinities) == 0) || len(engines) == 0 {
+		return engines
+	}
+
+	affinityMetaIds := selectAffinityMetaIds(affinities, metaData.MetaID, groupMetaData)
+	out := affinityEngines{}
+	for _, engine := range engines {
+		score, mismatch := scoreAffinityEngine(affinities, affinityMetaIds, engine)
+		if mismatch == nil {
+			_, mismatch = scoreAffinityEngine(reverseAffinities, reverseMetaIds, engine)
+		}
+		if mismatch != nil {
+			if reject != nil {
+				reject(engine, mismatch)
+			}
+			continue
+		}
+		out = append(out, &affinityEngine{engine: engine, score: score})
+	}
+
+	sort.Stable(out)
+	selectEngines := []*Engine{}
+	for _, affinityEngine := range out {
+		selectEngines = append(selectEngines, affinityEngine.engine)
+	}
+	return selectEngines
+}
+
+// selectReverseAffinities returns hard antiaffinities of other group metas which select metaData,
+// each is a rule of metaData against the containers of that meta.
+func selectReverseAffinities(metaData *MetaData, groupMetaData []*MetaData) ([]Affinity, [][]string) {
+
+	reverseAffinities := []Affinity{}
+	reverseMetaIds := [][]string{}
+	for _, groupMeta := range groupMetaData {
+		if groupMeta.MetaID == metaData.MetaID || len(groupMeta.Placement.Affinities) == 0 {
+			continue
+		}
+		affinities, err := ParseAffinities(groupMeta.Placement.Affinities)
+		if err != nil {
+			continue
+		}
+		for i := range affinities {
+			if affinities[i].hard && affinities[i].anti && affinities[i].MatchMeta(metaData) {
+				reverseAffinities = append(reverseAffinities, Affinity{anti: true, hard: true, metaName: groupMeta.Config.Name})
+				reverseMetaIds = append(reverseMetaIds, []string{groupMeta.MetaID})
+				break
+			}
+		}
+	}
+	return reverseAffinities, reverseMetaIds
+}
+
+// selectAffinityMetaIds returns metaids of group metaData selected by each affinity.
+func selectAffinityMetaIds(affinities []Affinity, metaid string, groupMetaData []*MetaData) [][]string {
+
+	affinityMetaIds := make([][]string, len(affinities))
+	for i := range affinities {
+		metaids := []string{}
+		for _, metaData := range groupMetaData {
+			if metaData.MetaID != metaid && affinities[i].MatchMeta(metaData) {
+				metaids = append(metaids, metaData.MetaID)
+			}
+		}
+		affinityMetaIds[i] = metaids
+	}
+	return affinityMetaIds
+}
+
+// scoreAffinityEngine returns engine soft affinities score.
+// mismatch is the first hard affinity which engine doesn't match, nil if engine matches all hard affinities.
+func scoreAffinityEngine(affinities []Affinity, affinityMetaIds [][]string, engine *Engine) (int, *Affinity) {
+
+	score := 0
+	for i := range affinities {
+		count := affinities[i].Count(engine, affinityMetaIds[i])
+		if affinities[i].hard {
+			if (!affinities[i].anti && count == 0) || (affinities[i].anti && count > 0) {
+				return 0, &affinities[i]
+			}
+			continue
+		}
+		if affinities[i].anti {
+			score = score - count
+		} else {
+			score = score + count
+		}
+	}
+	return score, nil
+}
@@ -0,0 +1,94 @@
+package cluster
+
+import "github.com/humpback/common/models"
+import "github.com/humpback/humpback-center/cluster/types"
+
+import (
+	"testing"
+)
+
+func TestParseAffinities(t *testing.T) {
+
+	tests := []struct {
+		affinities []types.Affinity
+		valid      bool
+		rule       string
+	}{
+		{[]types.Affinity{}, true, ""},
+		{[]types.Affinity{{Type: "affinity", MetaName: "db"}}, true, "hard affinity MetaName=db"},
+		{[]types.Affinity{{Type: "AntiAffinity", Mode: "soft", MetaName: "web"}}, true, "soft antiaffinity MetaName=web"},
+		{[]types.Affinity{{Type: "antiaffinity", Mode: "hard", MetaLabel: "app=web"}}, true, "hard antiaffinity MetaLabel=app=web"},
+		{[]types.Affinity{{Type: "affinity", MetaLabel: "cache"}}, true, "hard affinity MetaLabel=cache"},
+		{[]types.Affinity{{Type: "affinity", MetaName: "db", MetaLabel: "tier=data"}}, true, "hard affinity MetaName=db MetaLabel=tier=data"},
+		{[]types.Affinity{{Type: "near", MetaName: "db"}}, false, ""},
+		{[]types.Affinity{{Type: "affinity", Mode: "strict", MetaName: "db"}}, false, ""},
+		{[]types.Affinity{{Type: "affinity"}}, false, ""},
+		{[]types.Affinity{{Type: "affinity", MetaLabel: "=web"}}, false, ""},
+	}
+
+	for _, test := range tests {
+		affinities, err := ParseAffinities(test.affinities)
+		if test.valid && err != nil {
+			t.Errorf("ParseAffinities(%+v) error %s, expected valid", test.affinities, err)
+			continue
+		}
+		if !test.valid {
+			if err == nil {
+				t.Errorf("ParseAffinities(%+v) is valid, expected error", test.affinities)
+			}
+			continue
+		}
+		if len(affinities) != len(test.affinities) {
+			t.Errorf("ParseAffinities(%+v) returns %d affinities, expected %d", test.affinities, len(affinities), len(test.affinities))
+			continue
+		}
+		if len(affinities) > 0 && affinities[0].String() != test.rule {
+			t.Errorf("ParseAffinities(%+v) is %q, expected %q", test.affinities, affinities[0].String(), test.rule)
+		}
+	}
+}
+
+func TestAffinityEngines(t *testing.T) {
+
+	db := &MetaData{MetaBase: MetaBase{MetaID: "meta-db", Config: models.Container{Name: "db", Labels: map[string]string{"tier": "data"}}}}
+	web := &MetaData{MetaBase: MetaBase{MetaID: "meta-web", Config: models.Container{Name: "web"}}}
+	cache := &MetaData{MetaBase: MetaBase{MetaID: "meta-cache", Config: models.Container{Name: "cache"}}}
+	cache.Placement.Affinities = []types.Affinity{{Type: "antiaffinity", MetaName: "web"}}
+
+	engine1 := newTestEngine("192.168.2.1", 4, 8192, nil)
+	engine2 := newTestEngine("192.168.2.2", 4, 8192, nil)
+	engine3 := newTestEngine("192.168.2.3", 4, 8192, nil)
+	engine1.reservePending(db.MetaID, models.Container{Name: "db-1"})
+	engine2.reservePending(cache.MetaID, models.Container{Name: "cache-1"})
+	engine3.reservePending(db.MetaID, models.Container{Name: "db-2"})
+	engine3.reservePending(db.MetaID, models.Container{Name: "db-3"})
+	engines := []*Engine{engine1, engine2, engine3}
+	groupMetaData := []*MetaData{db, web, cache}
+
+	tests := []struct {
+		affinities []types.Affinity
+		ips        []string
+	}{
+		{[]types.Affinity{}, []string{"192.168.2.1", "192.168.2.3"}},
+		{[]types.Affinity{{Type: "affinity", MetaName: "db"}}, []string{"192.168.2.1", "192.168.2.3"}},
+		{[]types.Affinity{{Type: "antiaffinity", MetaLabel: "tier=data"}}, []string{}},
+		{[]types.Affinity{{Type: "affinity", Mode: "soft", MetaName: "db"}}, []string{"192.168.2.3", "192.168.2.1"}},
+		{[]types.Affinity{{Type: "antiaffinity", Mode: "soft", MetaName: "db"}}, []string{"192.168.2.1", "192.168.2.3"}},
+	}
+
+	for _, test := range tests {
+		affinities, err := ParseAffinities(test.affinities)
+		if err != nil {
+			t.Errorf("ParseAffinities(%+v) error %s", test.affinities, err)
+			continue
+		}
+		//cache hard antiaffinity of web applies to web, engine2 is never selected.
+		ips := []string{}
+		for _, engine := range AffinityEngines(affinities, web, groupMetaData, engines) {
+			ips = append(ips, engine.IP)
+		}
+		if !equalStrings(ips, test.ips) {
+			t.Errorf("AffinityEngines(%+v) is %v, expected %v", test.affinities, ips, test.ips)
+		}
+	}
+}
@@ -19,10 +19,12 @@ import (
 )
 
 // ContainerBaseConfig is exported
+// DynamicPorts: host ports allocated by center.
 type ContainerBaseConfig struct {
 	Index int `json:"Index"`
 	models.Container
-	MetaData *MetaData `json:"-"`
+	DynamicPorts []models.PortBinding `json:"DynamicPorts"`
+	MetaData     *MetaData            `json:"-"`
 }
 
 // SortContainerBaseConfigs is exported
@@ -45,18 +47,20 @@ func (containers SortContainerBaseConfigs) Less(i, j int) bool {
 
 // MetaBase is exported
 type MetaBase struct {
-	GroupID               string           `json:"GroupId"`
-	MetaID                string           `json:"MetaId"`
-	IsRemoveDelay         bool             `json:"IsRemoveDelay"`
-	IsRecovery            bool             `json:"IsRecovery"`
-	Instances             int              `json:"Instances"`
-	WebHooks              types.WebHooks   `json:"WebHooks"`
-	Placement             types.Placement  `json:"Placement"`
-	ImageTag              string           `json:"ImageTag"`
-	Config                models.Container `json:"Config"`
-	CreateAt              int64            `json:"CreateAt"`
-	LastUpdateAt          int64            `json:"LastUpdateAt"`
-	AvailableNodesChanged bool             `json:"AvailableNodesChanged"`
+	GroupID               string              `json:"GroupId"`
+	MetaID                string              `json:"MetaId"`
+	IsRemoveDelay         bool                `json:"IsRemoveDelay"`
+	IsRecovery            bool                `json:"IsRecovery"`
+	Priority              int                 `json:"Priority"`
+	MigratePolicy         types.MigratePolicy `json:"MigratePolicy"`
+	Instances             int                 `json:"Instances"`
+	WebHooks              types.WebHooks      `json:"WebHooks"`
+	Placement             types.Placement     `json:"Placement"`
+	ImageTag              string              `json:"ImageTag"`
+	Config                models.Container    `json:"Config"`
+	CreateAt              int64               `json:"CreateAt"`
+	LastUpdateAt          int64               `json:"LastUpdateAt"`
+	AvailableNodesChanged bool                `json:"AvailableNodesChanged"`
 }
 
 // MetaData is exported
@@ -300,12 +304,14 @@ func (cache *ContainersConfigCache) SetAvailableNodesChanged(metaid string, chan
 }
 
 // SetMetaData is exported
-func (cache *ContainersConfigCache) SetMetaData(metaid string, instances int, webhooks types.WebHooks, placement types.Placement, config models.Container, isremovedelay bool, isrecovery bool) {
+func (cache *ContainersConfigCache) SetMetaData(metaid string, instances int, webhooks types.WebHooks, placement types.Placement, config models.Container, isremovedelay bool, isrecovery bool, priority int, migratePolicy types.MigratePolicy) {
 
 	cache.Lock()
 	if metaData, ret := cache.data[metaid]; ret {
 		metaData.IsRemoveDelay = isremovedelay
 		metaData.IsRecovery = isrecovery
+		metaData.Priority = priority
+		metaData.MigratePolicy = migratePolicy
 		metaData.Instances = instances
 		metaData.WebHooks = webhooks
 		metaData.Placement = placement
@@ -349,7 +355,7 @@ func (cache *ContainersConfigCache) RemoveGroupMetaData(groupid string) bool {
 }
 
 // CreateMetaData is exported
-func (cache *ContainersConfigCache) CreateMetaData(groupid string, instances int, webhooks types.WebHooks, placement types.Placement, config models.Container, isremovedelay bool, isrecovery bool) (*MetaData, error) {
+func (cache *ContainersConfigCache) CreateMetaData(groupid string, instances int, webhooks types.WebHooks, placement types.Placement, config models.Container, isremovedelay bool, isrecovery bool, priority int, migratePolicy types.MigratePolicy) (*MetaData, error) {
 
 	cache.Lock()
 	defer cache.Unlock()
@@ -366,6 +372,8 @@ func (cache *ContainersConfigCache) CreateMetaData(groupid string, instances int
 			MetaID:        metaid,
 			IsRemoveDelay: isremovedelay,
 			IsRecovery:    isrecovery,
+			Priority:      priority,
+			MigratePolicy: migratePolicy,
 			Instances:     instances,
 			WebHooks:      webhooks,
 			Placement:     placement,
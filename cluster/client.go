@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"strconv"
 	"time"
 )
 
@@ -71,6 +72,64 @@ func (client *Client) GetDockerInfoRequest(ctx context.Context) (*types.Info, er
 	return dockerInfo, nil
 }
 
+// GetPerformanceRequest is exported
+// get agent host performance, cpu, memory and load figures.
+// agent which doesn't serve /v1/performance returns ErrClusterEngineAPINotSupported.
+func (client *Client) GetPerformanceRequest(ctx context.Context) (*ctypes.EnginePerformance, error) {
+
+	respPerformance, err := client.c.Get(ctx, "http://"+client.ApiAddr+"/v1/performance", nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	defer respPerformance.Close()
+	if respPerformance.StatusCode() == http.StatusNotFound {
+		return nil, ErrClusterEngineAPINotSupported
+	}
+
+	if respPerformance.StatusCode() >= http.StatusBadRequest {
+		return nil, fmt.Errorf("performance request, %s", ctypes.ParseHTTPResponseError(respPerformance))
+	}
+
+	performance := &ctypes.EnginePerformance{}
+	if err := respPerformance.JSON(performance); err != nil {
+		return nil, err
+	}
+	return performance, nil
+}
+
+// GetEventsRequest is exported
+// long-poll agent docker container events, agent returns events between since and until unix time when until is reached.
+// agent which doesn't serve /v1/events returns ErrClusterEngineAPINotSupported.
+func (client *Client) GetEventsRequest(ctx context.Context, since int64, until int64) ([]*ctypes.ContainerEvent, error) {
+
+	query := map[string][]string{
+		"type":  []string{"container"},
+		"since": []string{strconv.FormatInt(since, 10)},
+		"until": []string{strconv.FormatInt(until, 10)},
+	}
+
+	respEvents, err := client.c.Get(ctx, "http://"+client.ApiAddr+"/v1/events", query, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	defer respEvents.Close()
+	if respEvents.StatusCode() == http.StatusNotFound {
+		return nil, ErrClusterEngineAPINotSupported
+	}
+
+	if respEvents.StatusCode() >= http.StatusBadRequest {
+		return nil, fmt.Errorf("events request, %s", ctypes.ParseHTTPResponseError(respEvents))
+	}
+
+	events := []*ctypes.ContainerEvent{}
+	if err := respEvents.JSON(&events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
 // GetContainerRequest is exported
 // get a container type info.
 func (client *Client) GetContainerRequest(ctx context.Context, containerid string) (*types.ContainerJSON, error) {
@@ -13,22 +13,12 @@ import (
 	"fmt"
 	"math/rand"
 	"reflect"
-	"sort"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 )
 
-var pendingWaitForInterval = time.Duration(time.Second * 5)
-
-// pendingContainer is exported
-type pendingContainer struct {
-	GroupID string
-	Name    string
-	Config  models.Container
-}
-
 // Server is exported
 type Server struct {
 	Name string `json:"Name"`
@@ -50,24 +40,31 @@ type Group struct {
 // Cluster is exported
 type Cluster struct {
 	sync.RWMutex
-	Location          string
-	NotifySender      *notify.NotifySender
-	Discovery         *discovery.Discovery
-	overcommitRatio   float64
-	createRetry       int64
-	removeDelay       time.Duration
-	recoveryInterval  time.Duration
-	randSeed          *rand.Rand
-	nodeCache         *types.NodeCache
-	configCache       *ContainersConfigCache
-	migtatorCache     *MigrateContainersCache
-	enginesPool       *EnginesPool
-	hooksProcessor    *HooksProcessor
-	storageDriver     *storage.DataStorage
-	pendingContainers map[string]*pendingContainer
-	engines           map[string]*Engine
-	groups            map[string]*Group
-	stopCh            chan struct{}
+	Location         string
+	NotifySender     *notify.NotifySender
+	Discovery        *discovery.Discovery
+	overcommitRatio  float64
+	strategy         Strategy
+	portsAllocator   *HostPortsAllocator
+	createRetry      int64
+	usageWeight      bool
+	removeDelay      time.Duration
+	recoveryInterval time.Duration
+	randSeed         *rand.Rand
+	nodeCache        *types.NodeCache
+	configCache      *ContainersConfigCache
+	migtatorCache    *MigrateContainersCache
+	upgradeCache     *UpgradeContainersCache
+	enginesPool      *EnginesPool
+	enginesProber    *EnginesProber
+	hooksProcessor   *HooksProcessor
+	storageDriver    *storage.DataStorage
+	operationsQueue  *OperationsQueue
+	placementLock    sync.Mutex
+	quotaReserved    *QuotaReservations
+	engines          map[string]*Engine
+	groups           map[string]*Group
+	stopCh           chan struct{}
 }
 
 // NewCluster is exported
@@ -89,6 +86,24 @@ func NewCluster(driverOpts system.DriverOpts, notifySender *notify.NotifySender,
 		}
 	}
 
+	strategy, _ := NewStrategy(SpreadStrategyName)
+	if val, ret := driverOpts.String("strategy", ""); ret {
+		if s, err := NewStrategy(val); err != nil {
+			logger.WARN("[#cluster#] set strategy %s is invalid, expected spread, binpack or random.", val)
+		} else {
+			strategy = s
+		}
+	}
+
+	portRange, _ := ParsePortRange(DefaultPortRange)
+	if val, ret := driverOpts.String("portrange", ""); ret {
+		if r, err := ParsePortRange(val); err != nil {
+			logger.WARN("[#cluster#] set portrange %s is invalid, expected start-end.", val)
+		} else {
+			portRange = r
+		}
+	}
+
 	createretry := int64(0)
 	if val, ret := driverOpts.Int("createretry", ""); ret {
 		if val < 0 {
@@ -98,6 +113,13 @@ func NewCluster(driverOpts system.DriverOpts, notifySender *notify.NotifySender,
 		}
 	}
 
+	usageWeight := false
+	if val, ret := driverOpts.String("usageweight", ""); ret {
+		if value, err := strconv.ParseBool(val); err == nil {
+			usageWeight = value
+		}
+	}
+
 	removedelay := time.Duration(0)
 	if val, ret := driverOpts.String("removedelay", ""); ret {
 		if dur, err := time.ParseDuration(val); err == nil {
@@ -112,6 +134,40 @@ func NewCluster(driverOpts system.DriverOpts, notifySender *notify.NotifySender,
 		}
 	}
 
+	migrateConcurrency := int64(10)
+	if val, ret := driverOpts.Int("migrateconcurrency", ""); ret {
+		if val < 0 {
+			logger.WARN("[#cluster#] set migrateconcurrency should be larger than or equal to 0, %d is invalid.", val)
+		} else {
+			migrateConcurrency = val
+		}
+	}
+
+	migrateEngineConcurrency := int64(2)
+	if val, ret := driverOpts.Int("migrateengineconcurrency", ""); ret {
+		if val < 0 {
+			logger.WARN("[#cluster#] set migrateengineconcurrency should be larger than or equal to 0, %d is invalid.", val)
+		} else {
+			migrateEngineConcurrency = val
+		}
+	}
+
+	migrateParallelism := int64(2)
+	if val, ret := driverOpts.Int("migrateparallelism", ""); ret {
+		if val <= 0 {
+			logger.WARN("[#cluster#] set migrateparallelism should be larger than 0, %d is invalid.", val)
+		} else {
+			migrateParallelism = val
+		}
+	}
+
+	upgradedelay := 10 * time.Second
+	if val, ret := driverOpts.String("upgradedelay", ""); ret {
+		if dur, err := time.ParseDuration(val); err == nil {
+			upgradedelay = dur
+		}
+	}
+
 	recoveryInterval := 150 * time.Second
 	if val, ret := driverOpts.String("recoveryinterval", ""); ret {
 		if dur, err := time.ParseDuration(val); err == nil {
@@ -119,6 +175,45 @@ func NewCluster(driverOpts system.DriverOpts, notifySender *notify.NotifySender,
 		}
 	}
 
+	probeInterval := 10 * time.Second
+	if val, ret := driverOpts.String("probeinterval", ""); ret {
+		if dur, err := time.ParseDuration(val); err == nil {
+			probeInterval = dur
+		}
+	}
+
+	probeTimeout := 5 * time.Second
+	if val, ret := driverOpts.String("probetimeout", ""); ret {
+		if dur, err := time.ParseDuration(val); err == nil && dur > 0 {
+			probeTimeout = dur
+		}
+	}
+
+	probeFailures := int64(3)
+	if val, ret := driverOpts.Int("probefailures", ""); ret {
+		if val <= 0 {
+			logger.WARN("[#cluster#] set probefailures should be larger than 0, %d is invalid.", val)
+		} else {
+			probeFailures = val
+		}
+	}
+
+	probeSuccesses := int64(2)
+	if val, ret := driverOpts.Int("probesuccesses", ""); ret {
+		if val <= 0 {
+			logger.WARN("[#cluster#] set probesuccesses should be larger than 0, %d is invalid.", val)
+		} else {
+			probeSuccesses = val
+		}
+	}
+
+	probeGrace := 60 * time.Second
+	if val, ret := driverOpts.String("probegrace", ""); ret {
+		if dur, err := time.ParseDuration(val); err == nil {
+			probeGrace = dur
+		}
+	}
+
 	clusterLocation := ""
 	if val, ret := driverOpts.String("location", ""); ret {
 		clusterLocation = strings.TrimSpace(val)
@@ -130,7 +225,9 @@ func NewCluster(driverOpts system.DriverOpts, notifySender *notify.NotifySender,
 	}
 
 	enginesPool := NewEnginesPool()
-	migrateContainersCache := NewMigrateContainersCache(migratedelay)
+	enginesProber := NewEnginesProber(probeInterval, probeTimeout, int(probeFailures), int(probeSuccesses), probeGrace)
+	migrateContainersCache := NewMigrateContainersCache(migratedelay, int(migrateConcurrency), int(migrateEngineConcurrency), int(migrateParallelism))
+	upgradeContainersCache := NewUpgradeContainersCache(upgradedelay)
 	configCache, err := NewContainersConfigCache(cacheRoot)
 	if err != nil {
 		return nil, err
@@ -147,28 +244,36 @@ func NewCluster(driverOpts system.DriverOpts, notifySender *notify.NotifySender,
 	}
 
 	cluster := &Cluster{
-		Location:          clusterLocation,
-		NotifySender:      notifySender,
-		Discovery:         discovery,
-		overcommitRatio:   overcommitratio,
-		createRetry:       createretry,
-		removeDelay:       removedelay,
-		recoveryInterval:  recoveryInterval,
-		randSeed:          rand.New(rand.NewSource(time.Now().UTC().UnixNano())),
-		nodeCache:         types.NewNodeCache(),
-		configCache:       configCache,
-		migtatorCache:     migrateContainersCache,
-		enginesPool:       enginesPool,
-		hooksProcessor:    NewHooksProcessor(),
-		storageDriver:     storageDriver,
-		pendingContainers: make(map[string]*pendingContainer),
-		engines:           make(map[string]*Engine),
-		groups:            make(map[string]*Group),
-		stopCh:            make(chan struct{}),
+		Location:         clusterLocation,
+		NotifySender:     notifySender,
+		Discovery:        discovery,
+		overcommitRatio:  overcommitratio,
+		strategy:         strategy,
+		portsAllocator:   NewHostPortsAllocator(portRange),
+		createRetry:      createretry,
+		usageWeight:      usageWeight,
+		removeDelay:      removedelay,
+		recoveryInterval: recoveryInterval,
+		randSeed:         rand.New(rand.NewSource(time.Now().UTC().UnixNano())),
+		nodeCache:        types.NewNodeCache(),
+		configCache:      configCache,
+		migtatorCache:    migrateContainersCache,
+		upgradeCache:     upgradeContainersCache,
+		enginesPool:      enginesPool,
+		enginesProber:    enginesProber,
+		hooksProcessor:   NewHooksProcessor(),
+		storageDriver:    storageDriver,
+		operationsQueue:  NewOperationsQueue(),
+		quotaReserved:    NewQuotaReservations(),
+		engines:          make(map[string]*Engine),
+		groups:           make(map[string]*Group),
+		stopCh:           make(chan struct{}),
 	}
 
 	enginesPool.SetCluster(cluster)
+	enginesProber.SetCluster(cluster)
 	migrateContainersCache.SetCluster(cluster)
+	upgradeContainersCache.SetCluster(cluster)
 	return cluster, nil
 }
 
@@ -181,6 +286,7 @@ func (cluster *Cluster) Start() error {
 	}
 
 	cluster.configCache.Init()
+	cluster.migtatorCache.Resume()
 	if cluster.Discovery != nil {
 		if cluster.Location != "" {
 			logger.INFO("[#cluster#] cluster location: %s", cluster.Location)
@@ -188,6 +294,7 @@ func (cluster *Cluster) Start() error {
 		logger.INFO("[#cluster#] discovery service watching...")
 		cluster.Discovery.WatchNodes(cluster.stopCh, cluster.watchDiscoveryHandleFunc)
 		cluster.hooksProcessor.Start()
+		cluster.enginesProber.Start()
 		go cluster.recoveryContainersLoop()
 		return nil
 	}
@@ -202,6 +309,7 @@ func (cluster *Cluster) Stop() {
 
 	close(cluster.stopCh)
 	cluster.enginesPool.Release()
+	cluster.enginesProber.Close()
 	cluster.hooksProcessor.Close()
 	cluster.storageDriver.Close()
 	logger.INFO("[#cluster#] discovery service closed.")
@@ -390,6 +498,8 @@ func (cluster *Cluster) GetMetaEnginesContainers(metaData *MetaData, metaEngines
 		MetaID:        metaData.MetaID,
 		IsRemoveDelay: metaData.IsRemoveDelay,
 		IsRecovery:    metaData.IsRecovery,
+		Priority:      metaData.Priority,
+		MigratePolicy: metaData.MigratePolicy,
 		Instances:     metaData.Instances,
 		Placement:     metaData.Placement,
 		WebHooks:      metaData.WebHooks,
@@ -405,9 +515,10 @@ func (cluster *Cluster) GetMetaEnginesContainers(metaData *MetaData, metaEngines
 			if engine.IsHealthy() && engine.HasMeta(metaData.MetaID) {
 				if container := engine.Container(baseConfig.ID); container != nil {
 					groupContainer.Containers = append(groupContainer.Containers, &types.EngineContainer{
-						IP:        engine.IP,
-						HostName:  engine.Name,
-						Container: container.Config.Container,
+						IP:           engine.IP,
+						HostName:     engine.Name,
+						Container:    container.Config.Container,
+						DynamicPorts: baseConfig.DynamicPorts,
 					})
 					break
 				}
@@ -579,22 +690,29 @@ func (cluster *Cluster) RemoveGroup(groupid string) bool {
 
 	// remove group migrator's all meta.
 	cluster.migtatorCache.RemoveGroup(groupid)
-	// get group all metaData and clean metaData containers.
-	wgroup := sync.WaitGroup{}
+	// get group all metaData and clean metaData containers after their queued operations.
+	operations := []*Operation{}
 	groupMetaData := cluster.configCache.GetGroupMetaData(groupid)
 	for _, metaData := range groupMetaData {
-		wgroup.Add(1)
-		go func(mdata *MetaData) {
-			cluster.removeContainers(mdata, "")
+		mdata := metaData
+		operation := cluster.submitOperation(mdata, RemoveOperation, func(operation *Operation) (interface{}, error) {
+			removedContainers := cluster.removeContainers(mdata, "")
 			cluster.configCache.RemoveMetaData(mdata.MetaID)
 			cluster.submitHookEvent(mdata, RemoveMetaEvent)
-			wgroup.Done()
-		}(metaData)
+			return removedContainers, nil
+		})
+		operations = append(operations, operation)
+	}
+
+	for _, operation := range operations {
+		operation.Wait()
 	}
-	wgroup.Wait()
 
 	// remove metadata and group to cluster.
 	cluster.configCache.RemoveGroupMetaData(groupid)
+	if err := cluster.removeGroupQuota(groupid); err != nil {
+		logger.WARN("[#cluster#] remove group %s quota error, %s", groupid, err.Error())
+	}
 	cluster.Lock()
 	delete(cluster.groups, groupid) // remove group
 	logger.INFO("[#cluster#] removed group %s", groupid)
@@ -616,6 +734,11 @@ func (cluster *Cluster) RemoveGroup(groupid string) bool {
 //SetServerNodeLabels is exported
 func (cluster *Cluster) SetServerNodeLabels(server Server, labels map[string]string) error {
 
+	if _, err := ParseEngineResources(labels); err != nil {
+		logger.ERROR("[#cluster#] set node-labels error, %s", err.Error())
+		return ErrClusterNodeLabelsInvalid
+	}
+
 	engine := searchServerOfStorage(server, cluster.storageDriver.NodeStorage)
 	if engine == nil {
 		return ErrClusterServerNotFound
@@ -632,7 +755,7 @@ func (cluster *Cluster) SetServerNodeLabels(server Server, labels map[string]str
 				metaids := engine.MetaIds()
 				for _, metaid := range metaids {
 					if metaData := cluster.GetMetaData(metaid); metaData != nil {
-						if metaData.Placement.Constraints != nil && len(metaData.Placement.Constraints) > 0 {
+						if len(metaData.Placement.Constraints) > 0 || len(metaData.Placement.Preferences) > 0 {
 							logger.INFO("[#cluster#] meta %s enable available nodes changed.", metaid)
 							cluster.configCache.SetAvailableNodesChanged(metaid, true)
 						}
@@ -644,6 +767,40 @@ func (cluster *Cluster) SetServerNodeLabels(server Server, labels map[string]str
 	return err
 }
 
+//SetServerAvailability is exported
+//Pause and Drain exclude engine from new placements, Drain also migrates engine cluster containers to active engines.
+func (cluster *Cluster) SetServerAvailability(server Server, text string) error {
+
+	availability, err := ParseAvailability(text)
+	if err != nil {
+		logger.ERROR("[#cluster#] set availability error, %s", err.Error())
+		return ErrClusterAvailabilityInvalid
+	}
+
+	engine := searchServerOfStorage(server, cluster.storageDriver.NodeStorage)
+	if engine == nil {
+		return ErrClusterServerNotFound
+	}
+
+	err = cluster.storageDriver.NodeStorage.SetNodeAvailability(engine.IP, GetAvailabilityText(availability))
+	if err == nil {
+		//update engine availability in memory
+		if engine = cluster.GetEngine(engine.IP); engine != nil {
+			originalAvailability := engine.Availability()
+			if originalAvailability != availability {
+				logger.INFO("[#cluster#] set %s(%s) availability, %s", engine.IP, engine.Name, GetAvailabilityText(availability))
+				engine.SetAvailability(availability)
+				if availability == Drain {
+					cluster.migtatorCache.Drain(engine)
+				} else if originalAvailability == Drain { //cancel containers not migrated yet.
+					cluster.migtatorCache.Cancel(engine)
+				}
+			}
+		}
+	}
+	return err
+}
+
 func (cluster *Cluster) watchDiscoveryHandleFunc(added backends.Entries, removed backends.Entries, err error) {
 
 	if err != nil {
@@ -701,6 +858,25 @@ func (cluster *Cluster) OperateContainer(containerid string, action string) (str
 // if containerid is empty string so operate metaid's all containers
 func (cluster *Cluster) OperateContainers(metaid string, containerid string, action string) (*types.OperatedContainers, error) {
 
+	metaData, _, err := cluster.validateMetaData(metaid)
+	if err != nil {
+		logger.ERROR("[#cluster#] %s meta %s error, %s", action, metaid, err.Error())
+		return nil, err
+	}
+
+	operation := cluster.submitOperation(metaData, OperateOperation, func(operation *Operation) (interface{}, error) {
+		return cluster.operateContainers(metaid, containerid, action)
+	})
+
+	result, err := operation.Wait()
+	if err != nil {
+		return nil, err
+	}
+	return result.(*types.OperatedContainers), nil
+}
+
+func (cluster *Cluster) operateContainers(metaid string, containerid string, action string) (*types.OperatedContainers, error) {
+
 	metaData, engines, err := cluster.validateMetaData(metaid)
 	if err != nil {
 		logger.ERROR("[#cluster#] %s meta %s error, %s", action, metaid, err.Error())
@@ -765,20 +941,8 @@ func (cluster *Cluster) upgradeContainers(metaData *MetaData, engines []*Engine,
 		engineContainers[baseConfig.ID] = e
 	}
 
-	afterStop := false
-	if config.NetworkMode != "bridge" && config.NetworkMode != "nat" {
-		afterStop = true
-	}
-
-	if !afterStop {
-		for _, portBindings := range metaData.Config.Ports {
-			if portBindings.PublicPort != 0 {
-				afterStop = true
-				break
-			}
-		}
-	}
-
+	//old tag containers bind host ports, stop them to release ports before create new tag containers.
+	afterStop := isBindHostPorts(metaData.Config) || isBindHostPorts(config)
 	if afterStop { //after stop old tag containers.
 		if containerid, err := cluster.actionContainers("stop", engineContainers); err != nil {
 			cluster.configCache.RemoveContainerBaseConfig(metaData.MetaID, containerid)
@@ -818,8 +982,84 @@ func (cluster *Cluster) upgradeContainers(metaData *MetaData, engines []*Engine,
 	return &upgradeContainers, nil
 }
 
+// rollingUpgradeContainers upgrades meta containers of healthy engines batch by batch, completed containers are recovered
+// to original tag when a container upgraded failure. surge is disabled when meta binds host ports, containers are upgraded in place.
+func (cluster *Cluster) rollingUpgradeContainers(metaData *MetaData, engines []*Engine, imagetag string, rolling types.RollingUpgrade) (*types.UpgradeContainers, error) {
+
+	if rolling.MaxSurge > 0 && isBindHostPorts(metaData.Config) {
+		if rolling.MaxUnavailable <= 0 {
+			return nil, ErrClusterRollingUpgradeInvalid
+		}
+		logger.WARN("[#cluster#] upgrade %s containers bind host ports, max surge %d is ignored.", metaData.MetaID, rolling.MaxSurge)
+		rolling.MaxSurge = 0
+	}
+
+	containers := Containers{}
+	for _, engine := range engines {
+		if engine.IsHealthy() {
+			containers = append(containers, engine.Containers(metaData.MetaID)...)
+		}
+	}
+
+	upgradeCh := make(chan bool, 1)
+	upgrader := cluster.upgradeCache.Upgrade(upgradeCh, metaData.MetaID, imagetag, containers, rolling)
+	if upgrader == nil {
+		return nil, ErrClusterContainersUpgrading
+	}
+
+	if ret := <-upgradeCh; !ret {
+		return nil, upgrader.Error()
+	}
+	return upgrader.UpgradeContainers(), nil
+}
+
 // UpgradeContainers is exported
-func (cluster *Cluster) UpgradeContainers(metaid string, imagetag string) (*types.UpgradeContainers, error) {
+// rolling is nil, all new tag containers are created before original containers removed.
+func (cluster *Cluster) UpgradeContainers(metaid string, imagetag string, rolling *types.RollingUpgrade) (*types.UpgradeContainers, error) {
+
+	operation, err := cluster.submitUpgradeContainers(metaid, imagetag, rolling)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := operation.Wait()
+	if err != nil {
+		return nil, err
+	}
+	return result.(*types.UpgradeContainers), nil
+}
+
+// UpgradeContainersAsync is exported
+// queue upgrade operation of meta, return operation without waiting for it.
+func (cluster *Cluster) UpgradeContainersAsync(metaid string, imagetag string, rolling *types.RollingUpgrade) (*types.Operation, error) {
+
+	operation, err := cluster.submitUpgradeContainers(metaid, imagetag, rolling)
+	if err != nil {
+		return nil, err
+	}
+	return operation.Operation(), nil
+}
+
+func (cluster *Cluster) submitUpgradeContainers(metaid string, imagetag string, rolling *types.RollingUpgrade) (*Operation, error) {
+
+	if err := ValidateRollingUpgrade(rolling); err != nil {
+		logger.ERROR("[#cluster#] upgrade meta %s error, %s", metaid, err.Error())
+		return nil, err
+	}
+
+	metaData, _, err := cluster.validateMetaData(metaid)
+	if err != nil {
+		logger.ERROR("[#cluster#] upgrade meta %s error, %s", metaid, err.Error())
+		return nil, err
+	}
+
+	operation := cluster.submitOperation(metaData, UpgradeOperation, func(operation *Operation) (interface{}, error) {
+		return cluster.upgradeMetaContainers(metaid, imagetag, rolling)
+	})
+	return operation, nil
+}
+
+func (cluster *Cluster) upgradeMetaContainers(metaid string, imagetag string, rolling *types.RollingUpgrade) (*types.UpgradeContainers, error) {
 
 	metaData, engines, err := cluster.validateMetaData(metaid)
 	if err != nil {
@@ -831,14 +1071,22 @@ func (cluster *Cluster) UpgradeContainers(metaid string, imagetag string) (*type
 		return nil, fmt.Errorf("upgrade meta %s cancel, this tag has already in cluster", metaid)
 	}
 
+	cluster.migtatorCache.ClearPending(metaid)
+
 	config := metaData.Config
 	tagIndex := strings.LastIndex(config.Image, ":")
 	if tagIndex <= 0 {
 		return nil, fmt.Errorf("upgrade %s config tag invalid", metaid)
 	}
 
-	config.Image = config.Image[0:tagIndex] + ":" + imagetag
-	upgradeContainers, err := cluster.upgradeContainers(metaData, engines, config)
+	var upgradeContainers *types.UpgradeContainers
+	if rolling != nil {
+		upgradeContainers, err = cluster.rollingUpgradeContainers(metaData, engines, imagetag, *rolling)
+	} else {
+		config.Image = config.Image[0:tagIndex] + ":" + imagetag
+		upgradeContainers, err = cluster.upgradeContainers(metaData, engines, config)
+	}
+
 	if err != nil {
 		return nil, fmt.Errorf("upgrade %s failure, %s", metaid, err.Error())
 	}
@@ -879,12 +1127,52 @@ func (cluster *Cluster) RemoveContainersOfMetaName(groupid string, metaname stri
 // if containerid is empty string so remove metaid's all containers
 func (cluster *Cluster) RemoveContainers(metaid string, containerid string) (*types.RemovedContainers, error) {
 
+	operation, err := cluster.submitRemoveContainers(metaid, containerid)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := operation.Wait()
+	if err != nil {
+		return nil, err
+	}
+	return result.(*types.RemovedContainers), nil
+}
+
+// RemoveContainersAsync is exported
+// queue remove operation of meta, return operation without waiting for it.
+func (cluster *Cluster) RemoveContainersAsync(metaid string, containerid string) (*types.Operation, error) {
+
+	operation, err := cluster.submitRemoveContainers(metaid, containerid)
+	if err != nil {
+		return nil, err
+	}
+	return operation.Operation(), nil
+}
+
+func (cluster *Cluster) submitRemoveContainers(metaid string, containerid string) (*Operation, error) {
+
+	metaData, _, err := cluster.validateMetaData(metaid)
+	if err != nil {
+		logger.ERROR("[#cluster#] remove meta %s error, %s", metaid, err.Error())
+		return nil, err
+	}
+
+	operation := cluster.submitOperation(metaData, RemoveOperation, func(operation *Operation) (interface{}, error) {
+		return cluster.removeMetaContainers(metaid, containerid)
+	})
+	return operation, nil
+}
+
+func (cluster *Cluster) removeMetaContainers(metaid string, containerid string) (*types.RemovedContainers, error) {
+
 	metaData, _, err := cluster.validateMetaData(metaid)
 	if err != nil {
 		logger.ERROR("[#cluster#] remove meta %s error, %s", metaid, err.Error())
 		return nil, err
 	}
 
+	cluster.migtatorCache.ClearPending(metaid)
 	logger.INFO("[#cluster#] remove meta %s %s", metaid, containerid)
 	removedContainers := cluster.removeContainers(metaData, containerid)
 	cluster.submitHookEvent(metaData, RemoveMetaEvent)
@@ -900,6 +1188,21 @@ func (cluster *Cluster) RemoveContainers(metaid string, containerid string) (*ty
 // RecoveryContainers is exported
 func (cluster *Cluster) RecoveryContainers(metaid string) error {
 
+	metaData, _, err := cluster.validateMetaData(metaid)
+	if err != nil {
+		return fmt.Errorf("recovery meta %s %s", metaid, err)
+	}
+
+	operation := cluster.submitOperation(metaData, RecoveryOperation, func(operation *Operation) (interface{}, error) {
+		return nil, cluster.recoveryContainers(metaid)
+	})
+
+	_, err = operation.Wait()
+	return err
+}
+
+func (cluster *Cluster) recoveryContainers(metaid string) error {
+
 	metaData, engines, err := cluster.validateMetaData(metaid)
 	if err != nil {
 		return fmt.Errorf("recovery meta %s %s", metaid, err)
@@ -909,13 +1212,17 @@ func (cluster *Cluster) RecoveryContainers(metaid string) error {
 		return fmt.Errorf("recovery meta %s is disabled", metaData.MetaID)
 	}
 
+	if cluster.migtatorCache.Contains(metaData.MetaID) { //migration is waiting approval.
+		return fmt.Errorf("recovery meta %s %s", metaid, ErrClusterContainersMigrating)
+	}
+
 	baseConfigs := cluster.configCache.GetMetaDataBaseConfigs(metaData.MetaID)
 	for _, baseConfig := range baseConfigs {
 		if baseConfig.ID != "" {
 			found := false
 			for _, engine := range engines {
-				if engine.IsHealthy() && engine.HasContainer(baseConfig.ID) {
-					found = true
+				if (engine.IsHealthy() || engine.IsUnhealthy()) && engine.HasContainer(baseConfig.ID) {
+					found = true //unhealthy engine containers are kept until prober migrates them.
 					break
 				}
 			}
@@ -931,7 +1238,11 @@ func (cluster *Cluster) RecoveryContainers(metaid string) error {
 		if baseConfigsCount != -1 && metaData.Instances != baseConfigsCount {
 			var err error
 			if metaData.Instances > baseConfigsCount {
+				if err = cluster.reserveGroupQuota(metaData.GroupID, metaData.MetaID, metaData.Config.Name, metaData.Instances, metaData.Config); err != nil {
+					return fmt.Errorf("recovery meta %s %s", metaData.MetaID, err)
+				}
 				_, err = cluster.createContainers(metaData, metaData.Instances-baseConfigsCount, nil, metaData.Config)
+				cluster.releaseGroupQuota(metaData.GroupID, metaData.MetaID, metaData.Config.Name)
 			} else {
 				cluster.reduceContainers(metaData, baseConfigsCount-metaData.Instances)
 			}
@@ -945,25 +1256,96 @@ func (cluster *Cluster) RecoveryContainers(metaid string) error {
 // UpdateContainers is exported
 func (cluster *Cluster) UpdateContainers(metaid string, instances int, webhooks types.WebHooks, placement types.Placement, config models.Container, updateOption types.UpdateOption) (*types.CreatedContainers, error) {
 
+	operation, err := cluster.submitUpdateContainers(metaid, instances, webhooks, placement, config, updateOption)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := operation.Wait()
+	if err != nil {
+		return nil, err
+	}
+	return result.(*types.CreatedContainers), nil
+}
+
+// UpdateContainersAsync is exported
+// queue update operation of meta, return operation without waiting for it.
+func (cluster *Cluster) UpdateContainersAsync(metaid string, instances int, webhooks types.WebHooks, placement types.Placement, config models.Container, updateOption types.UpdateOption) (*types.Operation, error) {
+
+	operation, err := cluster.submitUpdateContainers(metaid, instances, webhooks, placement, config, updateOption)
+	if err != nil {
+		return nil, err
+	}
+	return operation.Operation(), nil
+}
+
+func (cluster *Cluster) submitUpdateContainers(metaid string, instances int, webhooks types.WebHooks, placement types.Placement, config models.Container, updateOption types.UpdateOption) (*Operation, error) {
+
 	if instances < 0 {
 		logger.ERROR("[#cluster#] update meta %s error, %s", metaid, ErrClusterContainersInstancesInvalid)
 		return nil, ErrClusterContainersInstancesInvalid
 	}
 
+	if _, err := NewStrategy(placement.Strategy); err != nil {
+		logger.ERROR("[#cluster#] update meta %s error, %s", metaid, err.Error())
+		return nil, err
+	}
+
+	if err := validatePortRange(placement); err != nil {
+		logger.ERROR("[#cluster#] update meta %s error, %s", metaid, err.Error())
+		return nil, err
+	}
+
+	if err := validateAffinities(placement); err != nil {
+		logger.ERROR("[#cluster#] update meta %s error, %s", metaid, err.Error())
+		return nil, err
+	}
+
+	if err := ValidateReducePolicy(placement.ReducePolicy); err != nil {
+		logger.ERROR("[#cluster#] update meta %s error, %s", metaid, err.Error())
+		return nil, err
+	}
+
+	if err := ValidateMigratePolicy(updateOption.MigratePolicy); err != nil {
+		logger.ERROR("[#cluster#] update meta %s error, %s", metaid, err.Error())
+		return nil, err
+	}
+
+	metaData, _, err := cluster.validateMetaData(metaid)
+	if err != nil {
+		logger.ERROR("[#cluster#] update meta %s error, %s", metaid, err.Error())
+		return nil, err
+	}
+
+	operation := cluster.submitOperation(metaData, UpdateOperation, func(operation *Operation) (interface{}, error) {
+		return cluster.updateContainers(metaid, instances, webhooks, placement, config, updateOption)
+	})
+	return operation, nil
+}
+
+func (cluster *Cluster) updateContainers(metaid string, instances int, webhooks types.WebHooks, placement types.Placement, config models.Container, updateOption types.UpdateOption) (*types.CreatedContainers, error) {
+
 	metaData, engines, err := cluster.validateMetaData(metaid)
 	if err != nil {
 		logger.ERROR("[#cluster#] update meta %s error, %s", metaid, err.Error())
 		return nil, err
 	}
 
+	cluster.migtatorCache.ClearPending(metaid)
 	if config.Name == "" {
 		config = metaData.Config
 	}
 
+	if err := cluster.reserveGroupQuota(metaData.GroupID, metaid, config.Name, instances, config); err != nil {
+		logger.ERROR("[#cluster#] update meta %s error, %s", metaid, err.Error())
+		return nil, err
+	}
+	defer cluster.releaseGroupQuota(metaData.GroupID, metaid, config.Name)
+
 	originalConfig := metaData.Config
 	originalPlacement := metaData.Placement
 	imageTag := getImageTag(config.Image)
-	cluster.configCache.SetMetaData(metaid, instances, webhooks, placement, config, updateOption.IsRemoveDelay, updateOption.IsRecovery)
+	cluster.configCache.SetMetaData(metaid, instances, webhooks, placement, config, updateOption.IsRemoveDelay, updateOption.IsRecovery, updateOption.Priority, updateOption.MigratePolicy)
 	cluster.configCache.SetImageTag(metaid, imageTag)
 	metaData = cluster.configCache.GetMetaData(metaid)
 	if metaData == nil {
@@ -976,7 +1358,7 @@ func (cluster *Cluster) UpdateContainers(metaid string, instances int, webhooks
 			logger.INFO("[#cluster#] update %s containers, reduce instances to %d.", config.Name, instances)
 			cluster.reduceContainers(metaData, originalInstances)
 		} else {
-			placementCompared := reflect.DeepEqual(originalPlacement, placement)
+			placementCompared := comparePlacement(originalPlacement, placement)
 			availableNodesChanged := metaData.AvailableNodesChanged
 			if !reflect.DeepEqual(originalConfig, config) || !placementCompared || availableNodesChanged {
 				//config or placement changed, re-create all containers.
@@ -1024,53 +1406,132 @@ func (cluster *Cluster) UpdateContainers(metaid string, instances int, webhooks
 // CreateContainers is exported
 func (cluster *Cluster) CreateContainers(groupid string, instances int, webhooks types.WebHooks, placement types.Placement, config models.Container, createOption types.CreateOption) (string, *types.CreatedContainers, error) {
 
+	operation, err := cluster.submitCreateContainers(groupid, instances, webhooks, placement, config, createOption)
+	if err != nil {
+		return "", nil, err
+	}
+
+	result, err := operation.Wait()
+	if err != nil {
+		return "", nil, err
+	}
+	return operation.MetaID(), result.(*types.CreatedContainers), nil
+}
+
+// CreateContainersAsync is exported
+// queue create operation of meta, return operation without waiting for it.
+// operation MetaId is set when meta is created.
+func (cluster *Cluster) CreateContainersAsync(groupid string, instances int, webhooks types.WebHooks, placement types.Placement, config models.Container, createOption types.CreateOption) (*types.Operation, error) {
+
+	operation, err := cluster.submitCreateContainers(groupid, instances, webhooks, placement, config, createOption)
+	if err != nil {
+		return nil, err
+	}
+	return operation.Operation(), nil
+}
+
+func (cluster *Cluster) submitCreateContainers(groupid string, instances int, webhooks types.WebHooks, placement types.Placement, config models.Container, createOption types.CreateOption) (*Operation, error) {
+
 	if instances <= 0 {
-		return "", nil, ErrClusterContainersInstancesInvalid
+		return nil, ErrClusterContainersInstancesInvalid
+	}
+
+	if _, err := NewStrategy(placement.Strategy); err != nil {
+		logger.ERROR("[#cluster#] create containers %s error, %s", config.Name, err.Error())
+		return nil, err
+	}
+
+	if err := validatePortRange(placement); err != nil {
+		logger.ERROR("[#cluster#] create containers %s error, %s", config.Name, err.Error())
+		return nil, err
+	}
+
+	if err := validateAffinities(placement); err != nil {
+		logger.ERROR("[#cluster#] create containers %s error, %s", config.Name, err.Error())
+		return nil, err
+	}
+
+	if err := ValidateReducePolicy(placement.ReducePolicy); err != nil {
+		logger.ERROR("[#cluster#] create containers %s error, %s", config.Name, err.Error())
+		return nil, err
+	}
+
+	if err := ValidateMigratePolicy(createOption.MigratePolicy); err != nil {
+		logger.ERROR("[#cluster#] create containers %s error, %s", config.Name, err.Error())
+		return nil, err
 	}
 
 	group := cluster.GetGroup(groupid)
 	engines := cluster.GetGroupEngines(groupid)
 	if group == nil || engines == nil {
 		logger.ERROR("[#cluster#] create containers %s error, %s", config.Name, ErrClusterGroupNotFound)
-		return "", nil, ErrClusterGroupNotFound
+		return nil, ErrClusterGroupNotFound
 	}
 
 	if len(engines) == 0 {
 		logger.ERROR("[#cluster#] create containers %s error, %s", config.Name, ErrClusterNoEngineAvailable)
-		return "", nil, ErrClusterNoEngineAvailable
+		return nil, ErrClusterNoEngineAvailable
 	}
 
-	var (
-		metaID  string
-		bCreate bool = true
-	)
+	//name uniqueness is checked in create operation, after prior operations of the same name.
+	metaID := ""
+	if createOption.IsReCreate {
+		if metaData := cluster.configCache.GetMetaDataOfName(groupid, config.Name); metaData != nil {
+			metaID = metaData.MetaID
+		}
+	}
+
+	operation := NewOperation(groupid, config.Name, metaID, CreateOperation, func(operation *Operation) (interface{}, error) {
+		_, createdContainers, err := cluster.createMetaContainers(operation, groupid, instances, webhooks, placement, config, createOption)
+		if err != nil {
+			return nil, err
+		}
+		return createdContainers, nil
+	})
+	return cluster.operationsQueue.Submit(operation), nil
+}
+
+// createMetaContainers is exported
+// executed by the create operation, metaid of operation is set once meta is made.
+func (cluster *Cluster) createMetaContainers(operation *Operation, groupid string, instances int, webhooks types.WebHooks, placement types.Placement, config models.Container, createOption types.CreateOption) (string, *types.CreatedContainers, error) {
 
 	if !createOption.IsReCreate {
 		if ret := cluster.cehckContainerNameUniqueness(groupid, config.Name); !ret {
 			logger.ERROR("[#cluster#] create containers %s error, %s", config.Name, ErrClusterCreateContainerNameConflict)
 			return "", nil, ErrClusterCreateContainerNameConflict
 		}
-	} else {
+	}
+
+	quotaMetaID := ""
+	if createOption.IsReCreate { //re-create replaces original meta containers.
+		if metaData := cluster.configCache.GetMetaDataOfName(groupid, config.Name); metaData != nil {
+			quotaMetaID = metaData.MetaID
+		}
+	}
+
+	if err := cluster.reserveGroupQuota(groupid, quotaMetaID, config.Name, instances, config); err != nil {
+		logger.ERROR("[#cluster#] create containers %s error, %s", config.Name, err.Error())
+		return "", nil, err
+	}
+	defer cluster.releaseGroupQuota(groupid, quotaMetaID, config.Name)
+
+	var (
+		metaID  string
+		bCreate bool = true
+	)
+
+	if createOption.IsReCreate {
 		if metaData := cluster.configCache.GetMetaDataOfName(groupid, config.Name); metaData != nil {
 			if len(webhooks) == 0 {
 				webhooks = metaData.WebHooks
 			}
 			if createOption.ForceRemove {
-				cluster.RemoveContainers(metaData.MetaID, "")
-				time.Sleep(pendingWaitForInterval)
+				cluster.removeMetaContainers(metaData.MetaID, "")
 			} else {
 				imageTag := getImageTag(config.Image)
 				if metaData.ImageTag == imageTag {
 					logger.WARN("[#cluster#] re-create %s containers %s tag %s eq.", metaData.MetaID, config.Name, imageTag)
-					for {
-						time.Sleep(pendingWaitForInterval)
-						if !cluster.containsPendingContainers(groupid, config.Name) {
-							break
-						}
-						logger.WARN("[#cluster#] re-create %s containers %s pending...", metaData.MetaID, config.Name)
-					}
-					cluster.RemoveContainers(metaData.MetaID, "")
-					time.Sleep(pendingWaitForInterval)
+					cluster.removeMetaContainers(metaData.MetaID, "")
 				} else {
 					metaID = metaData.MetaID
 					bCreate = false
@@ -1081,22 +1542,25 @@ func (cluster *Cluster) CreateContainers(groupid string, instances int, webhooks
 
 	createdContainers := types.CreatedContainers{}
 	if bCreate {
-		metaData, err := cluster.configCache.CreateMetaData(groupid, instances, webhooks, placement, config, createOption.IsRemoveDelay, createOption.IsRecovery)
+		metaData, err := cluster.configCache.CreateMetaData(groupid, instances, webhooks, placement, config, createOption.IsRemoveDelay, createOption.IsRecovery, createOption.Priority, createOption.MigratePolicy)
 		if err != nil {
 			if strings.Contains(err.Error(), "create meta conflict") {
-				newMetaID, containers, err := cluster.reCreateContainers(groupid, metaData.MetaID, instances, webhooks, placement, config, createOption)
+				operation.SetMetaID(metaData.MetaID)
+				containers, err := cluster.reCreateContainers(metaData.MetaID, instances, webhooks, placement, config, createOption)
 				if err != nil {
 					return "", nil, err
 				}
 				createdContainers = *containers
-				return newMetaID, &createdContainers, nil
+				return metaData.MetaID, &createdContainers, nil
 			}
 			logger.ERROR("[#cluster#] create containers %s error, %s", config.Name, ErrClusterContainersMetaCreateFailure)
 			return "", nil, ErrClusterContainersMetaCreateFailure
 		}
+		operation.SetMetaID(metaData.MetaID)
 		createdContainers, err = cluster.createContainers(metaData, instances, nil, config)
 		if len(createdContainers) == 0 {
 			cluster.configCache.RemoveMetaData(metaData.MetaID)
+			operation.SetMetaID("")
 			var resultErr string
 			if err != nil {
 				resultErr = err.Error()
@@ -1107,81 +1571,41 @@ func (cluster *Cluster) CreateContainers(groupid string, instances int, webhooks
 		metaID = metaData.MetaID
 		cluster.submitHookEvent(metaData, CreateMetaEvent)
 	} else {
-		newMetaID, containers, err := cluster.reCreateContainers(groupid, metaID, instances, webhooks, placement, config, createOption)
+		containers, err := cluster.reCreateContainers(metaID, instances, webhooks, placement, config, createOption)
 		if err != nil {
 			return "", nil, err
 		}
-		metaID = newMetaID
 		createdContainers = *containers
 	}
 	return metaID, &createdContainers, nil
 }
 
-// reContainers is exported
-func (cluster *Cluster) reCreateContainers(groupid string, metaID string, instances int, webhooks types.WebHooks, placement types.Placement, config models.Container, createOption types.CreateOption) (string, *types.CreatedContainers, error) {
-
-	retries := cluster.createRetry
-
-RECREATE:
-	for {
-		if !cluster.containsPendingContainers(groupid, config.Name) {
-			break
-		}
-		time.Sleep(pendingWaitForInterval)
-		logger.WARN("[#cluster#] re-create %s containers %s pending...", metaID, config.Name)
-	}
+// reCreateContainers is exported
+// update meta containers, called by the running create operation of meta.
+func (cluster *Cluster) reCreateContainers(metaID string, instances int, webhooks types.WebHooks, placement types.Placement, config models.Container, createOption types.CreateOption) (*types.CreatedContainers, error) {
 
 	updateOption := types.UpdateOption{
 		IsRemoveDelay: createOption.IsRemoveDelay,
 		IsRecovery:    createOption.IsRecovery,
+		Priority:      createOption.Priority,
+		MigratePolicy: createOption.MigratePolicy,
 	}
-	containers, err := cluster.UpdateContainers(metaID, instances, webhooks, placement, config, updateOption)
+	containers, err := cluster.updateContainers(metaID, instances, webhooks, placement, config, updateOption)
 	if err != nil || len(*containers) == 0 {
-		if err != nil {
-			if strings.Contains(err.Error(), "cluster containers state is setting") {
-				retries = retries - 1
-				if retries > 0 {
-					time.Sleep(pendingWaitForInterval)
-					goto RECREATE
-				}
-			} else if strings.Contains(err.Error(), "cluster metadata not found") {
-				retries = retries - 1
-				if retries > 0 {
-					pMetaData := cluster.configCache.GetMetaDataOfName(groupid, config.Name)
-					if pMetaData != nil && pMetaData.MetaID != metaID {
-						metaID = pMetaData.MetaID
-						time.Sleep(pendingWaitForInterval)
-						goto RECREATE
-					}
-				}
-			}
-		}
 		logger.ERROR("[#cluster#] re-create %s containers %s error, %s", metaID, config.Name, ErrClusterCreateContainerFailure)
-		return "", nil, ErrClusterCreateContainerFailure
+		return nil, ErrClusterCreateContainerFailure
 	}
-	return metaID, containers, nil
+	return containers, nil
 }
 
 // reduceContainers is exported
 func (cluster *Cluster) reduceContainers(metaData *MetaData, instances int) {
 
-	cluster.Lock()
-	cluster.pendingContainers[metaData.Config.Name] = &pendingContainer{
-		GroupID: metaData.GroupID,
-		Name:    metaData.Config.Name,
-		Config:  metaData.Config,
-	}
-	cluster.Unlock()
-
 	for ; instances > 0; instances-- {
 		if _, _, err := cluster.reduceContainer(metaData); err != nil {
 			logger.ERROR("[#cluster#] reduce container %s, error:%s", metaData.Config.Name, err.Error())
 		}
 	}
-
-	cluster.Lock()
-	delete(cluster.pendingContainers, metaData.Config.Name)
-	cluster.Unlock()
 }
 
 // reduceContainer is exported
@@ -1192,12 +1616,12 @@ func (cluster *Cluster) reduceContainer(metaData *MetaData) (*Engine, *Container
 		return nil, nil, ErrClusterNoEngineAvailable
 	}
 
-	reduceEngines := selectReduceEngines(metaData.MetaID, engines)
+	reduceEngines := selectReduceEngines(metaData, engines)
 	if len(reduceEngines) == 0 {
 		return nil, nil, ErrClusterNoEngineAvailable
 	}
 
-	sort.Sort(reduceEngines)
+	sortReduceEngines(metaData, reduceEngines)
 	reduceEngine := reduceEngines[0]
 	engine := reduceEngine.Engine()
 	container := reduceEngine.ReduceContainer()
@@ -1210,14 +1634,6 @@ func (cluster *Cluster) reduceContainer(metaData *MetaData) (*Engine, *Container
 // removeContainers is exported
 func (cluster *Cluster) removeContainers(metaData *MetaData, containerid string) *types.RemovedContainers {
 
-	cluster.Lock()
-	cluster.pendingContainers[metaData.Config.Name] = &pendingContainer{
-		GroupID: metaData.GroupID,
-		Name:    metaData.Config.Name,
-		Config:  metaData.Config,
-	}
-	cluster.Unlock()
-
 	removedContainers := types.RemovedContainers{}
 	if engines := cluster.GetGroupEngines(metaData.GroupID); engines != nil {
 		foundContainer := false
@@ -1245,24 +1661,12 @@ func (cluster *Cluster) removeContainers(metaData *MetaData, containerid string)
 			}
 		}
 	}
-
-	cluster.Lock()
-	delete(cluster.pendingContainers, metaData.Config.Name)
-	cluster.Unlock()
 	return &removedContainers
 }
 
 // createContainers is exported
 func (cluster *Cluster) createContainers(metaData *MetaData, instances int, priorities *EnginePriorities, config models.Container) (types.CreatedContainers, error) {
 
-	cluster.Lock()
-	cluster.pendingContainers[config.Name] = &pendingContainer{
-		GroupID: metaData.GroupID,
-		Name:    config.Name,
-		Config:  config,
-	}
-	cluster.Unlock()
-
 	var resultErr error
 	createdContainers := types.CreatedContainers{}
 	filter := NewEnginesFilter()
@@ -1283,7 +1687,7 @@ func (cluster *Cluster) createContainers(metaData *MetaData, instances int, prio
 		}
 		engine, container, err := cluster.createContainer(metaData, filter, priorities, containerConfig)
 		if err != nil {
-			if err == ErrClusterNoEngineAvailable || strings.Index(err.Error(), " not found") >= 0 {
+			if err == ErrClusterNoEngineAvailable || err == ErrClusterNoPlatformEngineAvailable || err == ErrClusterMaxPerEngineExceeded || err == ErrClusterHostPortsConflict || strings.Index(err.Error(), " not found") >= 0 {
 				resultErr = err
 				logger.ERROR("[#cluster#] create container %s, error:%s", containerConfig.Name, err.Error())
 				continue
@@ -1295,7 +1699,7 @@ func (cluster *Cluster) createContainers(metaData *MetaData, instances int, prio
 			}
 			if err != nil {
 				resultErr = err
-				if err == ErrClusterNoEngineAvailable {
+				if engine == nil {
 					logger.ERROR("[#cluster#] create container %s, error:%s", containerConfig.Name, err.Error())
 				} else {
 					logger.ERROR("[#cluster#] engine %s, create container %s, error:%s", engine.IP, containerConfig.Name, err.Error())
@@ -1305,10 +1709,6 @@ func (cluster *Cluster) createContainers(metaData *MetaData, instances int, prio
 		}
 		createdContainers = createdContainers.SetCreatedPair(engine.IP, engine.Name, container.Config.Container)
 	}
-
-	cluster.Lock()
-	delete(cluster.pendingContainers, config.Name)
-	cluster.Unlock()
 	return createdContainers, resultErr
 }
 
@@ -1321,28 +1721,41 @@ func (cluster *Cluster) createContainer(metaData *MetaData, filter *EnginesFilte
 	}
 
 	for _, engine := range engines {
-		if engine.IsHealthy() && engine.HasMeta(metaData.MetaID) {
+		if engine.IsActive() && engine.HasMeta(metaData.MetaID) {
 			filter.SetAllocEngine(engine)
 		}
 	}
 
-	var engine *Engine
-	if priorities != nil {
-		engine = priorities.Select()
+	//dynamic ports are allocated again on the selected engine.
+	config = resetDynamicPorts(metaData, config)
+	//engines of all metas are selected one by one, selected engine is reserved for config until container is created.
+	cluster.placementLock.Lock()
+	engine, victims, err := cluster.selectCreateEngine(metaData, filter, priorities, engines, config)
+	if err != nil {
+		cluster.placementLock.Unlock()
+		return nil, nil, err
 	}
+	engine.reservePending(metaData.MetaID, config)
+	cluster.placementLock.Unlock()
+	defer engine.releasePending(config.Name)
 
-	if engine == nil {
-		selectEngines := cluster.selectEngines(engines, filter, config)
-		if len(selectEngines) == 0 {
-			return nil, nil, ErrClusterNoEngineAvailable
-		}
-		selectEngines = cluster.selectPlacementEngines(selectEngines, filter, &metaData.Placement)
-		if len(selectEngines) == 0 {
-			return nil, nil, ErrClusterNoEngineAvailable
+	if victims != nil {
+		if err = cluster.evictVictims(metaData, victims); err != nil {
+			filter.SetFailEngine(engine)
+			return engine, nil, err
 		}
-		engine = selectEngines[0]
 	}
 
+	//wait for a create slot of busy engine, selected engine is kept.
+	filter.AcquireEngine(engine)
+	defer filter.ReleaseEngine(engine)
+	config, err = cluster.portsAllocator.Allocate(engine, metaData, config)
+	if err != nil {
+		filter.SetFailEngine(engine)
+		return engine, nil, err
+	}
+
+	defer cluster.portsAllocator.Release(engine, config)
 	container, err := engine.CreateContainer(config)
 	if err != nil {
 		filter.SetFailEngine(engine)
@@ -1351,47 +1764,258 @@ func (cluster *Cluster) createContainer(metaData *MetaData, filter *EnginesFilte
 	return engine, container, nil
 }
 
-// selectEngines is exported
-func (cluster *Cluster) selectEngines(engines []*Engine, filter *EnginesFilter, config models.Container) []*Engine {
+// selectCreateEngine is exported
+// Select an engine of meta for config, priorities engine first.
+// Return victims to evict if the engine is preempted, no placement engine has room for config.
+// filter records why other engines are rejected.
+func (cluster *Cluster) selectCreateEngine(metaData *MetaData, filter *EnginesFilter, priorities *EnginePriorities, engines []*Engine, config models.Container) (*Engine, *preemptVictims, error) {
 
-	selectEngines := []*Engine{}
-	for _, engine := range engines {
-		if engine.IsHealthy() {
-			selectEngines = append(selectEngines, engine)
+	if priorities != nil {
+		//paused or draining priority engine, or its host ports are bound, select an other engine.
+		if engine := priorities.Select(); engine != nil {
+			if priorityEngines := cluster.selectActiveEngines([]*Engine{engine}, filter); len(priorityEngines) > 0 {
+				if priorityEngines = cluster.selectHostPortsEngines(priorityEngines, filter, config); len(priorityEngines) > 0 {
+					return engine, nil, nil
+				}
+			}
+		}
+	}
+
+	platformEngines := cluster.selectPlatformEngines(engines, filter, metaData.Placement.Platforms)
+	if len(platformEngines) == 0 {
+		return nil, nil, ErrClusterNoPlatformEngineAvailable
+	}
+	platformEngines = cluster.selectMaxPerEngines(metaData, filter, platformEngines)
+	if len(platformEngines) == 0 {
+		return nil, nil, ErrClusterMaxPerEngineExceeded
+	}
+	platformEngines = cluster.selectHostPortsEngines(platformEngines, filter, config)
+	if len(platformEngines) == 0 {
+		return nil, nil, ErrClusterHostPortsConflict
+	}
+	//no placement engine has room for config, evict containers of lower priority metas.
+	if victims := cluster.preemptEngine(metaData, platformEngines, engines, config); victims != nil {
+		return victims.engine, victims, nil
+	}
+	strategy := cluster.selectStrategy(metaData)
+	selectEngines := cluster.selectEngines(platformEngines, filter, strategy, config)
+	if len(selectEngines) == 0 {
+		return nil, nil, ErrClusterNoEngineAvailable
+	}
+	selectEngines = cluster.selectPlacementEngines(metaData, selectEngines, engines, filter)
+	if len(selectEngines) == 0 {
+		return nil, nil, ErrClusterNoEngineAvailable
+	}
+	selectEngines = cluster.selectAllocEngines(selectEngines, filter, strategy)
+	//constraints and alloc engines fallback may select filtered engines again.
+	selectEngines = cluster.selectActiveEngines(selectEngines, filter)
+	if len(selectEngines) == 0 {
+		return nil, nil, ErrClusterNoEngineAvailable
+	}
+	selectEngines = cluster.selectPlatformEngines(selectEngines, filter, metaData.Placement.Platforms)
+	if len(selectEngines) == 0 {
+		return nil, nil, ErrClusterNoPlatformEngineAvailable
+	}
+	selectEngines = cluster.selectMaxPerEngines(metaData, filter, selectEngines)
+	if len(selectEngines) == 0 {
+		return nil, nil, ErrClusterMaxPerEngineExceeded
+	}
+	selectEngines = cluster.selectHostPortsEngines(selectEngines, filter, config)
+	if len(selectEngines) == 0 {
+		return nil, nil, ErrClusterHostPortsConflict
+	}
+
+	for i, engine := range selectEngines[1:] {
+		filter.Reject(engine, fmt.Sprintf("ranked %d by %s strategy", i+2, strategy.Name()))
+	}
+	return selectEngines[0], nil, nil
+}
+
+// selectStrategy is exported
+// Return meta placement strategy, empty or invalid strategy is cluster default strategy.
+func (cluster *Cluster) selectStrategy(metaData *MetaData) Strategy {
+
+	if strings.TrimSpace(metaData.Placement.Strategy) != "" {
+		strategy, err := NewStrategy(metaData.Placement.Strategy)
+		if err == nil {
+			return strategy
+		}
+		logger.WARN("[#cluster#] meta %s strategy %s invalid, use %s.", metaData.MetaID, metaData.Placement.Strategy, cluster.strategy.Name())
+	}
+	return cluster.strategy
+}
+
+// comparePlacement returns true if placements select the same engines for containers.
+// reduce policy only applies when scaling down, changing it doesn't re-create containers.
+func comparePlacement(original types.Placement, placement types.Placement) bool {
+
+	original.ReducePolicy = ""
+	placement.ReducePolicy = ""
+	return reflect.DeepEqual(original, placement)
+}
+
+// validatePortRange returns ErrClusterPortRangeInvalid if placement port range is set and invalid.
+func validatePortRange(placement types.Placement) error {
+
+	if strings.TrimSpace(placement.PortRange) != "" {
+		if _, err := ParsePortRange(placement.PortRange); err != nil {
+			return ErrClusterPortRangeInvalid
 		}
 	}
+	return nil
+}
 
+// validateAffinities returns ErrClusterAffinitiesInvalid if placement affinities are invalid.
+func validateAffinities(placement types.Placement) error {
+
+	if _, err := ParseAffinities(placement.Affinities); err != nil {
+		logger.ERROR("[#cluster#] placement affinities error, %s", err.Error())
+		return ErrClusterAffinitiesInvalid
+	}
+	return nil
+}
+
+// selectEngines is exported
+// Return active engines which have room for config, sorted by strategy.
+// all active engines are kept if no engine has room, the best engine is tried anyway.
+func (cluster *Cluster) selectEngines(engines []*Engine, filter *EnginesFilter, strategy Strategy, config models.Container) []*Engine {
+
+	selectEngines := cluster.selectActiveEngines(engines, filter)
 	if len(selectEngines) == 0 {
 		return selectEngines //return empty engines
 	}
 
-	weightedEngines := selectWeightdEngines(selectEngines, config)
+	weightedEngines := rankEngines(strategy, selectEngines, config, cluster.usageWeight, filter)
 	if len(weightedEngines) > 0 {
-		sort.Sort(weightedEngines)
-		selectEngines = weightedEngines.Engines()
+		selectEngines = weightedEngines
 	}
+	return selectEngines
+}
 
-	if len(selectEngines) > 0 {
-		filterEngines := filter.Filter(selectEngines)
-		if len(filterEngines) > 0 {
-			selectEngines = filterEngines
+// selectActiveEngines is exported
+// Return engines which accept new containers.
+func (cluster *Cluster) selectActiveEngines(engines []*Engine, filter *EnginesFilter) []*Engine {
+
+	selectEngines := []*Engine{}
+	for _, engine := range engines {
+		if engine.IsActive() {
+			selectEngines = append(selectEngines, engine)
+		} else if !engine.IsHealthy() {
+			filter.Reject(engine, fmt.Sprintf("engine state is %s", engine.State()))
 		} else {
-			filterEngines = filter.AllocEngines()
-			if len(filterEngines) > 0 {
-				selectEngines = filterEngines
+			filter.Reject(engine, fmt.Sprintf("engine availability is %s", GetAvailabilityText(engine.Availability())))
+		}
+	}
+	return selectEngines
+}
+
+// selectAllocEngines is exported
+// Prefer engines which have no meta containers and no failed creates of filter, if strategy filters alloc engines.
+// all engines are filtered, alloc engines are selected again, fail engines are the last choice,
+// so create error of a fail engine is returned rather than cluster no engine available error.
+func (cluster *Cluster) selectAllocEngines(engines []*Engine, filter *EnginesFilter, strategy Strategy) []*Engine {
+
+	if strategy.FilterAllocEngines() {
+		if filterEngines := filter.Filter(engines); len(filterEngines) > 0 {
+			for _, engine := range engines {
+				if !containsEngine(filterEngines, engine) {
+					filter.Reject(engine, "already allocated meta containers or create failed")
+				}
 			}
-			for i := len(selectEngines) - 1; i > 0; i-- {
-				j := cluster.randSeed.Intn(i + 1)
-				selectEngines[i], selectEngines[j] = selectEngines[j], selectEngines[i]
+			return filterEngines
+		}
+	}
+
+	if filterEngines := filter.FilterFailEngines(engines); len(filterEngines) > 0 {
+		for _, engine := range engines {
+			if !containsEngine(filterEngines, engine) {
+				filter.Reject(engine, "create failed")
 			}
 		}
+		return filterEngines
+	}
+	logger.INFO("[#cluster#] alloc engines, select fail engines")
+	return engines
+}
+
+// selectPlatformEngines is exported
+func (cluster *Cluster) selectPlatformEngines(engines []*Engine, filter *EnginesFilter, platforms []types.Platform) []*Engine {
+
+	if len(platforms) == 0 {
+		return engines
+	}
+
+	selectEngines := []*Engine{}
+	for _, engine := range engines {
+		if MatchPlatforms(platforms, engine) {
+			selectEngines = append(selectEngines, engine)
+		} else {
+			logger.INFO("[#cluster#] platform engines filter, %s(%s) %s/%s", engine.IP, engine.Name, engine.OSType, engine.Architecture)
+			filter.Reject(engine, fmt.Sprintf("platform %s/%s mismatch %s", engine.OSType, engine.Architecture, PlatformsString(platforms)))
+		}
+	}
+
+	if len(selectEngines) == 0 {
+		logger.ERROR("[#cluster#] platform engines, no engine matches %s", PlatformsString(platforms))
+	}
+	return selectEngines
+}
+
+// selectMaxPerEngines is exported
+// Return engines which meta containers count is less than placement max instances per engine.
+func (cluster *Cluster) selectMaxPerEngines(metaData *MetaData, filter *EnginesFilter, engines []*Engine) []*Engine {
+
+	maxPerEngine := metaData.Placement.MaxPerEngine
+	if maxPerEngine <= 0 {
+		return engines
+	}
+
+	selectEngines := []*Engine{}
+	for _, engine := range engines {
+		if engine.ScheduledContainers(metaData.MetaID) < maxPerEngine {
+			selectEngines = append(selectEngines, engine)
+		} else {
+			logger.INFO("[#cluster#] max per engine filter, %s(%s) %d", engine.IP, engine.Name, maxPerEngine)
+			filter.Reject(engine, fmt.Sprintf("max per engine %d reached", maxPerEngine))
+		}
+	}
+
+	if len(selectEngines) == 0 {
+		logger.ERROR("[#cluster#] max per engine, meta %s has %d containers on every engine", metaData.MetaID, maxPerEngine)
+	}
+	return selectEngines
+}
+
+// selectHostPortsEngines is exported
+// Return engines which running containers don't bind the host ports of config.
+func (cluster *Cluster) selectHostPortsEngines(engines []*Engine, filter *EnginesFilter, config models.Container) []*Engine {
+
+	hostPorts := configHostPorts(config)
+	if len(hostPorts) == 0 {
+		return engines
+	}
+
+	selectEngines := []*Engine{}
+	for _, engine := range engines {
+		if conflicts := engine.HostPorts().Conflicts(hostPorts); len(conflicts) > 0 {
+			logger.INFO("[#cluster#] host ports engines filter, %s(%s) %s", engine.IP, engine.Name, strings.Join(conflicts, ","))
+			filter.Reject(engine, fmt.Sprintf("host ports %s are already bound", strings.Join(conflicts, ",")))
+		} else {
+			selectEngines = append(selectEngines, engine)
+		}
+	}
+
+	if len(selectEngines) == 0 {
+		logger.ERROR("[#cluster#] host ports engines, %s ports are bound on every engine", config.Name)
 	}
 	return selectEngines
 }
 
 // selectPlacementEngines is exported
-func (cluster *Cluster) selectPlacementEngines(engines []*Engine, filter *EnginesFilter, placement *types.Placement) []*Engine {
+// groupEngines is meta group all engines, used to count spread preferences.
+func (cluster *Cluster) selectPlacementEngines(metaData *MetaData, engines []*Engine, groupEngines []*Engine, filter *EnginesFilter) []*Engine {
 
+	placement := &metaData.Placement
 	selectEngines := []*Engine{}
 	if placement.Constraints != nil && len(placement.Constraints) > 0 {
 		constraints, err := ParseConstraints(placement.Constraints)
@@ -1406,6 +2030,7 @@ func (cluster *Cluster) selectPlacementEngines(engines []*Engine, filter *Engine
 				logger.INFO("[#cluster#] placement engines, %s(%s)", engine.IP, engine.Name)
 			} else {
 				logger.INFO("[#cluster#] placement engines filter, %s(%s)", engine.IP, engine.Name)
+				filter.Reject(engine, fmt.Sprintf("constraints mismatch %s", strings.Join(placement.Constraints, ", ")))
 			}
 		}
 		if len(selectEngines) == 0 {
@@ -1428,29 +2053,47 @@ func (cluster *Cluster) selectPlacementEngines(engines []*Engine, filter *Engine
 		selectEngines = engines
 		logger.INFO("[#cluster#] skip placement engines.")
 	}
-	return selectEngines
-}
 
-// containsPendingContainers is exported
-func (cluster *Cluster) containsPendingContainers(groupid string, name string) bool {
+	if len(selectEngines) > 0 {
+		affinities, err := ParseAffinities(placement.Affinities)
+		if err != nil {
+			logger.ERROR("[#cluster#] placement affinities error, %s", err.Error())
+			return []*Engine{} //return empty engines
+		}
+		groupMetaData := cluster.configCache.GetGroupMetaData(metaData.GroupID)
+		selectEngines = selectAffinityEngines(affinities, metaData, groupMetaData, selectEngines, func(engine *Engine, affinity *Affinity) {
+			logger.INFO("[#cluster#] placement affinity engines filter, %s(%s) %s", engine.IP, engine.Name, affinity.String())
+			filter.Reject(engine, fmt.Sprintf("affinity mismatch %s", affinity.String()))
+		})
+		if len(selectEngines) == 0 {
+			logger.ERROR("[#cluster#] placement affinities, no engine matches %s affinities", metaData.Config.Name)
+			return selectEngines
+		}
+	}
 
-	cluster.RLock()
-	defer cluster.RUnlock()
-	for _, pendingContainer := range cluster.pendingContainers {
-		if pendingContainer.GroupID == groupid && pendingContainer.Name == name {
-			return true
+	if placement.Preferences != nil && len(placement.Preferences) > 0 && len(selectEngines) > 0 {
+		preferences, err := ParsePreferences(placement.Preferences)
+		if err != nil {
+			logger.ERROR("[#cluster#] placement preferences error, %s", err.Error())
+			return []*Engine{} //return empty engines
+		}
+		spreadEngines := SpreadEngines(preferences, metaData.MetaID, selectEngines, groupEngines)
+		for _, engine := range selectEngines {
+			if !containsEngine(spreadEngines, engine) {
+				filter.Reject(engine, "spread preferences, more containers on this engine label value")
+			}
+		}
+		selectEngines = spreadEngines
+		for _, engine := range selectEngines {
+			logger.INFO("[#cluster#] placement spread engines, %s(%s)", engine.IP, engine.Name)
 		}
 	}
-	return false
+	return selectEngines
 }
 
 // cehckContainerNameUniqueness is exported
 func (cluster *Cluster) cehckContainerNameUniqueness(groupid string, name string) bool {
 
-	if ret := cluster.containsPendingContainers(groupid, name); ret {
-		return false
-	}
-
 	metaData := cluster.configCache.GetMetaDataOfName(groupid, name)
 	if metaData != nil {
 		return false
@@ -1466,13 +2109,9 @@ func (cluster *Cluster) validateMetaData(metaid string) (*MetaData, []*Engine, e
 		return nil, nil, err
 	}
 
-	if ret := cluster.migtatorCache.Contains(metaData.MetaID); ret {
+	if ret := cluster.migtatorCache.Migrating(metaData.MetaID); ret {
 		return nil, nil, ErrClusterContainersMigrating
 	}
-
-	if ret := cluster.containsPendingContainers(metaData.GroupID, metaData.Config.Name); ret {
-		return nil, nil, ErrClusterContainersSetting
-	}
 	return metaData, engines, nil
 }
 
@@ -1488,11 +2127,15 @@ func (cluster *Cluster) submitHookEvent(metaData *MetaData, hookEvent HookEvent)
 		if engine.IsHealthy() {
 			containers := engine.Containers(metaData.MetaID)
 			for _, container := range containers {
-				hookContainers = append(hookContainers, &HookContainer{
+				hookContainer := &HookContainer{
 					IP:        engine.IP,
 					Name:      engine.Name,
 					Container: container.Config.Container,
-				})
+				}
+				if container.BaseConfig != nil {
+					hookContainer.DynamicPorts = container.BaseConfig.DynamicPorts
+				}
+				hookContainers = append(hookContainers, hookContainer)
 			}
 		}
 	}
@@ -1513,6 +2156,9 @@ func (cluster *Cluster) recoveryContainersLoop() {
 				for _, group := range groups {
 					groupMetaData := cluster.configCache.GetGroupMetaData(group.ID)
 					for _, metaData := range groupMetaData {
+						if cluster.operationsQueue.Busy(metaData.GroupID, metaData.Config.Name, metaData.MetaID) || cluster.migtatorCache.Contains(metaData.MetaID) {
+							continue //meta has queued operations or migrating containers, recovery next time.
+						}
 						metaids = append(metaids, metaData.MetaID)
 						if _, engines, err := cluster.GetMetaDataEngines(metaData.MetaID); err == nil {
 							for _, engine := range engines {
@@ -0,0 +1,129 @@
+package cluster
+
+import "github.com/humpback/common/models"
+import "github.com/humpback/humpback-center/cluster/storage"
+import "github.com/humpback/humpback-center/cluster/types"
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+const testGroupID = "group-1234567890"
+
+func newTestEngine(ip string, cpus int64, memory int64, labels map[string]string) *Engine {
+
+	if labels == nil {
+		labels = map[string]string{}
+	}
+
+	return &Engine{
+		IP:           ip,
+		Name:         "host-" + ip,
+		Cpus:         cpus,
+		Memory:       memory,
+		OSType:       "linux",
+		Architecture: "x86_64",
+		NodeLabels:   labels,
+		EngineLabels: map[string]string{},
+		containers:   make(map[string]*Container),
+		pendings:     make(map[string]*pendingContainer),
+		replacings:   make(map[string]bool),
+		availability: Active,
+		state:        StateHealthy,
+	}
+}
+
+func newTestCluster(t *testing.T, engines ...*Engine) *Cluster {
+
+	configCache, err := NewContainersConfigCache(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewContainersConfigCache error %s", err)
+	}
+
+	storageDriver, err := storage.NewDataStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewDataStorage error %s", err)
+	}
+
+	if err := storageDriver.Open(); err != nil {
+		t.Fatalf("storage driver open error %s", err)
+	}
+	t.Cleanup(func() { storageDriver.Close() })
+
+	cluster := &Cluster{
+		strategy:        &SpreadStrategy{},
+		randSeed:        rand.New(rand.NewSource(time.Now().UnixNano())),
+		configCache:     configCache,
+		storageDriver:   storageDriver,
+		operationsQueue: NewOperationsQueue(),
+		quotaReserved:   NewQuotaReservations(),
+		engines:         make(map[string]*Engine),
+		groups:          make(map[string]*Group),
+	}
+
+	group := &Group{ID: testGroupID, Servers: []Server{}}
+	for _, engine := range engines {
+		cluster.engines[engine.IP] = engine
+		group.Servers = append(group.Servers, Server{IP: engine.IP})
+	}
+	cluster.groups[group.ID] = group
+	return cluster
+}
+
+// scheduleTestContainers selects engines of instances same as createContainers, selected engines are reserved.
+func scheduleTestContainers(t *testing.T, cluster *Cluster, metaData *MetaData, instances int) []string {
+
+	ips := []string{}
+	filter := NewEnginesFilter()
+	engines := cluster.GetGroupEngines(metaData.GroupID)
+	for index := 1; index <= instances; index++ {
+		config := metaData.Config
+		config.Name = fmt.Sprintf("%s-%d", config.Name, index)
+		engine, _, err := cluster.selectCreateEngine(metaData, filter, nil, engines, config)
+		if err != nil {
+			t.Fatalf("select create engine of instance %d error %s", index, err)
+		}
+		engine.reservePending(metaData.MetaID, config)
+		filter.SetAllocEngine(engine)
+		ips = append(ips, engine.IP)
+	}
+	return ips
+}
+
+func equalStrings(values []string, expected []string) bool {
+
+	if len(values) != len(expected) {
+		return false
+	}
+
+	for i := range values {
+		if values[i] != expected[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestSelectCreateEngineSpreadPreferences(t *testing.T) {
+
+	cluster := newTestCluster(t,
+		newTestEngine("192.168.2.1", 8, 8192, map[string]string{"rack": "a"}),
+		newTestEngine("192.168.2.2", 8, 8192, map[string]string{"rack": "a"}),
+		newTestEngine("192.168.2.3", 8, 8192, map[string]string{"rack": "a"}),
+		newTestEngine("192.168.2.4", 8, 8192, map[string]string{"rack": "b"}),
+	)
+
+	metaData := &MetaData{MetaBase: MetaBase{GroupID: testGroupID, MetaID: "meta-web", Config: models.Container{Name: "web", CPUShares: 1, Memory: 256}}}
+	metaData.Placement.Preferences = []types.Preference{{Spread: types.Spread{SpreadDescriptor: "node.labels.rack"}}}
+	racks := map[string]int{}
+	for _, ip := range scheduleTestContainers(t, cluster, metaData, 4) {
+		racks[cluster.engines[ip].NodeLabels["rack"]]++
+	}
+
+	if racks["a"] != 2 || racks["b"] != 2 {
+		t.Errorf("spread instances of rack a %d and rack b %d, expected 2 and 2", racks["a"], racks["b"])
+	}
+}
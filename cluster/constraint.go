@@ -1,15 +1,26 @@
 package cluster
 
+import units "github.com/docker/go-units"
+
 import (
 	"fmt"
 	"net"
 	"regexp"
+	"strconv"
 	"strings"
 )
 
 const (
 	eq = iota
 	noteq
+	gte
+	lte
+	gt
+	lt
+	in
+	notin
+	exists
+	notexists
 
 	// NodeLabelsPrefix is the constraint key prefix for node labels.
 	NodeLabelsPrefix = "node.labels."
@@ -21,9 +32,15 @@ var (
 	alphaNumeric = regexp.MustCompile(`^(?i)[a-z_][a-z0-9\-_.]+$`)
 	// value can be alphanumeric and some special characters. it shouldn't container
 	// current or future operators like '>, <, ~', etc.
+	// version values like '1.3.0' or '17.06.2-ce' are also accepted.
 	valuePattern = regexp.MustCompile(`^(?i)[a-z0-9:\-_\s\.\*\(\)\?\+\[\]\\\^\$\|\/]+$`)
+	// set expr is in the form of "key in (a,b,c)" or "key notin (a,b,c)"
+	setPattern = regexp.MustCompile(`^(?i)(\S+)\s+(in|notin)\s*\((.*)\)$`)
 	// operators defines list of accepted operators
-	operators = []string{"==", "!="}
+	// order matters, '>=' and '<=' must be checked before '>' and '<'.
+	operators = []string{"==", "!=", ">=", "<=", ">", "<"}
+	// compareKeys defines list of keys accepted by comparison operators, except labels keys.
+	compareKeys = []string{"node.cpus", "node.memory", "node.dockerversion", "node.appversion"}
 )
 
 // Constraint defines a constraint.
@@ -31,13 +48,54 @@ type Constraint struct {
 	key      string
 	operator int
 	exp      string
+	exps     []string
+	regexps  []*regexp.Regexp
 }
 
 // ParseConstraints parses list of constraints.
+// value is matched exactly (case insensitive), as a glob if it contains '*' or '?',
+// or as a regular expression when it's in the form of '/regex/'.
+// labels existence is checked in the form of 'node.labels.key' or '!node.labels.key'.
+// comparison operators '>, >=, <, <=' are accepted by labels keys and engine resources keys,
+// node.cpus and node.memory compare numbers, node.dockerversion and node.appversion compare versions.
 func ParseConstraints(constraints []string) ([]Constraint, error) {
 
 	exprs := []Constraint{}
 	for _, c := range constraints {
+		c = strings.TrimSpace(c)
+		// label existence expr, "key" or "!key"
+		if key := strings.TrimPrefix(c, "!"); alphaNumeric.MatchString(key) {
+			if !isLabelsKey(key) {
+				return nil, fmt.Errorf("key '%s' is invalid, existence check only supports %s and %s", key, NodeLabelsPrefix, EngineLabelsPrefix)
+			}
+			operator := exists
+			if strings.HasPrefix(c, "!") {
+				operator = notexists
+			}
+			exprs = append(exprs, Constraint{key: key, operator: operator})
+			continue
+		}
+
+		// set expr, "key in (a,b,c)"
+		if parts := setPattern.FindStringSubmatch(c); parts != nil {
+			key := strings.TrimSpace(parts[1])
+			if matched := alphaNumeric.MatchString(key); !matched {
+				return nil, fmt.Errorf("key '%s' is invalid", key)
+			}
+			operator := in
+			if strings.EqualFold(parts[2], "notin") {
+				operator = notin
+			}
+			expr := Constraint{key: key, operator: operator, exp: strings.TrimSpace(parts[3])}
+			for _, value := range strings.Split(parts[3], ",") {
+				if err := expr.addExp(strings.TrimSpace(value)); err != nil {
+					return nil, err
+				}
+			}
+			exprs = append(exprs, expr)
+			continue
+		}
+
 		found := false
 		// each expr is in the form of "key op value"
 		for i, op := range operators {
@@ -59,47 +117,307 @@ func ParseConstraints(constraints []string) ([]Constraint, error) {
 			}
 
 			part1 := strings.TrimSpace(parts[1])
-
-			// validate Value
-			matched = valuePattern.MatchString(part1)
-			if matched == false {
-				return nil, fmt.Errorf("value '%s' is invalid", part1)
+			expr := Constraint{key: part0, operator: i, exp: part1}
+			if expr.isCompare() && !isLabelsKey(part0) && !containsFold(compareKeys, part0) {
+				return nil, fmt.Errorf("key '%s' is invalid, operator %s only supports %s, labels keys", part0, op, strings.Join(compareKeys, ", "))
+			}
+			if err := expr.addExp(part1); err != nil {
+				return nil, err
 			}
-			// TODO(dongluochen): revisit requirements to see if globing or regex are useful
-			exprs = append(exprs, Constraint{key: part0, operator: i, exp: part1})
+			exprs = append(exprs, expr)
 
 			found = true
 			break // found an op, move to next entry
 		}
 		if !found {
-			return nil, fmt.Errorf("constraint expected one operator from %s", strings.Join(operators, ", "))
+			return nil, fmt.Errorf("constraint expected one operator from %s, in, notin or a labels key", strings.Join(operators, ", "))
 		}
 	}
 	return exprs, nil
 }
 
+// addExp validates and appends a constraint value.
+// glob and '/regex/' values are compiled to case insensitive regular expressions.
+func (c *Constraint) addExp(value string) error {
+
+	// validate Value
+	if matched := valuePattern.MatchString(value); !matched {
+		return fmt.Errorf("value '%s' is invalid", value)
+	}
+
+	var pattern string
+	if !c.isCompare() {
+		if len(value) > 2 && strings.HasPrefix(value, "/") && strings.HasSuffix(value, "/") {
+			pattern = value[1 : len(value)-1]
+		} else if strings.ContainsAny(value, "*?") {
+			pattern = regexp.QuoteMeta(value)
+			pattern = strings.Replace(pattern, `\*`, ".*", -1)
+			pattern = strings.Replace(pattern, `\?`, ".", -1)
+		}
+	}
+
+	var re *regexp.Regexp
+	if pattern != "" {
+		var err error
+		if re, err = regexp.Compile("(?i)^(?:" + pattern + ")$"); err != nil {
+			return fmt.Errorf("value '%s' is invalid, %s", value, err)
+		}
+	}
+	c.exps = append(c.exps, value)
+	c.regexps = append(c.regexps, re)
+	return nil
+}
+
 // Match checks if the Constraint matches the target strings.
 func (c *Constraint) Match(whats ...string) bool {
 
 	var match bool
-	// full string match
-	for _, what := range whats {
-		// case insensitive compare
-		if strings.EqualFold(c.exp, what) {
-			match = true
+	for i, exp := range c.exps {
+		for _, what := range whats {
+			if re := c.regexps[i]; re != nil {
+				match = re.MatchString(what)
+			} else {
+				// case insensitive compare
+				match = strings.EqualFold(exp, what)
+			}
+			if match {
+				break
+			}
+		}
+		if match {
 			break
 		}
 	}
 
 	switch c.operator {
-	case eq:
+	case eq, in:
 		return match
-	case noteq:
+	case noteq, notin:
 		return !match
 	}
 	return false
 }
 
+// MatchExists checks if the Constraint matches the label existence.
+func (c *Constraint) MatchExists(labels map[string]string, label string) bool {
+
+	_, ret := labels[label]
+	if c.operator == exists {
+		return ret
+	}
+	return !ret
+}
+
+// matchIP checks if the Constraint matches the engine ip.
+// constraint value can be a single IP address, a CIDR subnet or a glob/regex string.
+func (c *Constraint) matchIP(ip string) bool {
+
+	engineIP := net.ParseIP(ip)
+	var match bool
+	for i, exp := range c.exps {
+		if c.regexps[i] != nil {
+			// glob or regex, node.ip == 192.168.2.*
+			match = c.regexps[i].MatchString(ip)
+		} else if addr := net.ParseIP(exp); addr != nil {
+			// single IP address, node.ip == 2001:db8::2
+			match = addr.Equal(engineIP)
+		} else if _, subnet, err := net.ParseCIDR(exp); err == nil {
+			// CIDR subnet, node.ip != 210.8.4.0/24
+			match = subnet.Contains(engineIP)
+		} else {
+			// reject constraint with malformed address/network
+			return false
+		}
+		if match {
+			break
+		}
+	}
+
+	if c.operator == eq || c.operator == in {
+		return match
+	}
+	return !match
+}
+
+// isCompare returns true if the Constraint operator is a comparison operator.
+func (c *Constraint) isCompare() bool {
+
+	return c.operator == gt || c.operator == gte || c.operator == lt || c.operator == lte
+}
+
+// MatchCompare checks if the Constraint matches a comparison result.
+// result is the value compared to the constraint value, -1, 0 or 1.
+func (c *Constraint) MatchCompare(result int) bool {
+
+	switch c.operator {
+	case gt:
+		return result > 0
+	case gte:
+		return result >= 0
+	case lt:
+		return result < 0
+	case lte:
+		return result <= 0
+	}
+	return false
+}
+
+// matchNumber checks if the Constraint matches a number value.
+func (c *Constraint) matchNumber(value float64, exp string, memory bool) bool {
+
+	if !c.isCompare() {
+		return c.Match(strconv.FormatFloat(value, 'f', -1, 64))
+	}
+
+	var (
+		expValue float64
+		err      error
+	)
+
+	if memory {
+		expValue, err = parseMemoryValue(exp)
+	} else {
+		expValue, err = strconv.ParseFloat(exp, 64)
+	}
+
+	if err != nil {
+		return false
+	}
+	return c.MatchCompare(compareNumbers(value, expValue))
+}
+
+// matchVersion checks if the Constraint matches a version value.
+func (c *Constraint) matchVersion(version string) bool {
+
+	if !c.isCompare() {
+		return c.Match(version)
+	}
+
+	result, err := compareVersions(version, c.exp)
+	if err != nil {
+		return false
+	}
+	return c.MatchCompare(result)
+}
+
+// matchLabel checks if the Constraint matches a label value.
+// comparison operators compare numbers first, then versions.
+func (c *Constraint) matchLabel(labels map[string]string, label string) bool {
+
+	value, ret := labels[label]
+	if !c.isCompare() {
+		return c.Match(value)
+	}
+
+	if !ret {
+		return false
+	}
+
+	if v1, err := strconv.ParseFloat(value, 64); err == nil {
+		if v2, err := strconv.ParseFloat(c.exp, 64); err == nil {
+			return c.MatchCompare(compareNumbers(v1, v2))
+		}
+	}
+
+	result, err := compareVersions(value, c.exp)
+	if err != nil {
+		return false
+	}
+	return c.MatchCompare(result)
+}
+
+// parseMemoryValue returns memory size in MB.
+// value without unit is MB, like engine memory and container memory, otherwise '16g', '512m'.
+func parseMemoryValue(value string) (float64, error) {
+
+	if size, err := strconv.ParseFloat(value, 64); err == nil {
+		return size, nil
+	}
+
+	size, err := units.RAMInBytes(value)
+	if err != nil {
+		return 0, err
+	}
+	return float64(size) / 1024.0 / 1024.0, nil
+}
+
+func compareNumbers(v1 float64, v2 float64) int {
+
+	if v1 > v2 {
+		return 1
+	} else if v1 < v2 {
+		return -1
+	}
+	return 0
+}
+
+// compareVersions compares two semantic versions, like '1.3.0', 'v1.3.7' or '17.06.2-ce'.
+// pre-release and build suffix are ignored, missing parts are equivalent to 0.
+func compareVersions(v1 string, v2 string) (int, error) {
+
+	parts1, err := parseVersion(v1)
+	if err != nil {
+		return 0, err
+	}
+
+	parts2, err := parseVersion(v2)
+	if err != nil {
+		return 0, err
+	}
+
+	for i := 0; i < len(parts1) || i < len(parts2); i++ {
+		var n1, n2 int64
+		if i < len(parts1) {
+			n1 = parts1[i]
+		}
+		if i < len(parts2) {
+			n2 = parts2[i]
+		}
+		if n1 != n2 {
+			return compareNumbers(float64(n1), float64(n2)), nil
+		}
+	}
+	return 0, nil
+}
+
+func parseVersion(version string) ([]int64, error) {
+
+	value := strings.TrimPrefix(strings.ToLower(strings.TrimSpace(version)), "v")
+	if index := strings.IndexAny(value, "-+"); index >= 0 {
+		value = value[:index]
+	}
+
+	if value == "" {
+		return nil, fmt.Errorf("version '%s' is invalid", version)
+	}
+
+	parts := []int64{}
+	for _, part := range strings.Split(value, ".") {
+		n, err := strconv.ParseInt(part, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("version '%s' is invalid", version)
+		}
+		parts = append(parts, n)
+	}
+	return parts, nil
+}
+
+func containsFold(values []string, key string) bool {
+
+	for _, value := range values {
+		if strings.EqualFold(value, key) {
+			return true
+		}
+	}
+	return false
+}
+
+func isLabelsKey(key string) bool {
+
+	return (len(key) > len(NodeLabelsPrefix) && strings.EqualFold(key[:len(NodeLabelsPrefix)], NodeLabelsPrefix)) ||
+		(len(key) > len(EngineLabelsPrefix) && strings.EqualFold(key[:len(EngineLabelsPrefix)], EngineLabelsPrefix))
+}
+
 // MatchConstraints returns true if the node satisfies the given constraints.
 func MatchConstraints(constraints []Constraint, engine *Engine) bool {
 
@@ -123,25 +441,25 @@ func MatchConstraints(constraints []Constraint, engine *Engine) bool {
 				return false
 			}
 		case strings.EqualFold(constraint.key, "node.ip"):
-			engineIP := net.ParseIP(engine.IP)
-			// single IP address, node.ip == 2001:db8::2
-			if ip := net.ParseIP(constraint.exp); ip != nil {
-				ipEq := ip.Equal(engineIP)
-				if (ipEq && constraint.operator != eq) || (!ipEq && constraint.operator == eq) {
-					return false
-				}
-				continue
+			if !constraint.matchIP(engine.IP) {
+				return false
 			}
-			// CIDR subnet, node.ip != 210.8.4.0/24
-			if _, subnet, err := net.ParseCIDR(constraint.exp); err == nil {
-				within := subnet.Contains(engineIP)
-				if (within && constraint.operator != eq) || (!within && constraint.operator == eq) {
-					return false
-				}
-				continue
+		case strings.EqualFold(constraint.key, "node.cpus"):
+			if !constraint.matchNumber(float64(engine.Cpus), constraint.exp, false) {
+				return false
+			}
+		case strings.EqualFold(constraint.key, "node.memory"):
+			if !constraint.matchNumber(float64(engine.Memory), constraint.exp, true) {
+				return false
+			}
+		case strings.EqualFold(constraint.key, "node.dockerversion"):
+			if !constraint.matchVersion(engine.DockerVersion) {
+				return false
+			}
+		case strings.EqualFold(constraint.key, "node.appversion"):
+			if !constraint.matchVersion(engine.AppVersion) {
+				return false
 			}
-			// reject constraint with malformed address/network
-			return false
 		/*
 			case strings.EqualFold(constraint.key, "node.role"):
 				if !constraint.Match(n.Role.String()) {
@@ -170,29 +488,27 @@ func MatchConstraints(constraints []Constraint, engine *Engine) bool {
 			}
 		// node labels constraint in form like 'node.labels.key==value'
 		case len(constraint.key) > len(NodeLabelsPrefix) && strings.EqualFold(constraint.key[:len(NodeLabelsPrefix)], NodeLabelsPrefix):
-			if engine.NodeLabels == nil {
-				if !constraint.Match("") {
+			label := constraint.key[len(NodeLabelsPrefix):]
+			if constraint.operator == exists || constraint.operator == notexists {
+				if !constraint.MatchExists(engine.NodeLabels, label) {
 					return false
 				}
 				continue
 			}
-			label := constraint.key[len(NodeLabelsPrefix):]
 			// label itself is case sensitive
-			val := engine.NodeLabels[label]
-			if !constraint.Match(val) {
+			if !constraint.matchLabel(engine.NodeLabels, label) {
 				return false
 			}
 		// engine labels constraint in form like 'engine.labels.key!=value'
 		case len(constraint.key) > len(EngineLabelsPrefix) && strings.EqualFold(constraint.key[:len(EngineLabelsPrefix)], EngineLabelsPrefix):
-			if engine.EngineLabels == nil {
-				if !constraint.Match("") {
+			label := constraint.key[len(EngineLabelsPrefix):]
+			if constraint.operator == exists || constraint.operator == notexists {
+				if !constraint.MatchExists(engine.EngineLabels, label) {
 					return false
 				}
 				continue
 			}
-			label := constraint.key[len(EngineLabelsPrefix):]
-			val := engine.EngineLabels[label]
-			if !constraint.Match(val) {
+			if !constraint.matchLabel(engine.EngineLabels, label) {
 				return false
 			}
 		default:
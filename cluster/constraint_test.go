@@ -0,0 +1,217 @@
+package cluster
+
+import (
+	"testing"
+)
+
+func TestParseConstraints(t *testing.T) {
+
+	tests := []struct {
+		constraints []string
+		valid       bool
+	}{
+		{[]string{"node.hostname==host-1"}, true},
+		{[]string{"node.hostname != host-1"}, true},
+		{[]string{"node.hostname==host-*"}, true},
+		{[]string{"node.hostname==/host-[0-9]+/"}, true},
+		{[]string{"node.hostname==/host-[0-9+/"}, false},
+		{[]string{"node.ip==192.168.2.0/24"}, true},
+		{[]string{"node.platform.os in (linux, windows)"}, true},
+		{[]string{"node.platform.arch notin (arm64)"}, true},
+		{[]string{"node.labels.zone"}, true},
+		{[]string{"!engine.labels.gpu"}, true},
+		{[]string{"node.hostname"}, false},
+		{[]string{"node.hostname~=host-1"}, false},
+		{[]string{"node.hostname==host>1"}, false},
+		{[]string{"==host-1"}, false},
+		{[]string{"-node==host-1"}, false},
+		{[]string{"node.labels.zone==zone-1", "node.platform.os==linux"}, true},
+		{[]string{"node.labels.zone==zone-1", "node.hostname"}, false},
+	}
+
+	for _, test := range tests {
+		constraints, err := ParseConstraints(test.constraints)
+		if test.valid && err != nil {
+			t.Errorf("ParseConstraints(%q) error %s, expected valid", test.constraints, err)
+		}
+		if !test.valid && err == nil {
+			t.Errorf("ParseConstraints(%q) is valid, expected error", test.constraints)
+		}
+		if test.valid && len(constraints) != len(test.constraints) {
+			t.Errorf("ParseConstraints(%q) returns %d constraints, expected %d", test.constraints, len(constraints), len(test.constraints))
+		}
+	}
+}
+
+func TestMatchConstraints(t *testing.T) {
+
+	engine := &Engine{
+		ID:            "engine-1",
+		Name:          "host-12",
+		IP:            "192.168.2.12",
+		Cpus:          8,
+		Memory:        16384,
+		DockerVersion: "17.06.2-ce",
+		AppVersion:    "v1.3.7",
+		OSType:        "linux",
+		Architecture:  "x86_64",
+		NodeLabels:    map[string]string{"zone": "zone-1", "disk": "ssd"},
+		EngineLabels:  map[string]string{"node": "node-12"},
+	}
+
+	tests := []struct {
+		constraints []string
+		match       bool
+	}{
+		{[]string{}, true},
+		{[]string{"node.id==engine-1"}, true},
+		{[]string{"node.hostname==HOST-12"}, true},
+		{[]string{"node.hostname!=host-12"}, false},
+		{[]string{"node.hostname==host-*"}, true},
+		{[]string{"node.hostname==host-?"}, false},
+		{[]string{"node.hostname==/host-[0-9]+/"}, true},
+		{[]string{"node.ip==192.168.2.12"}, true},
+		{[]string{"node.ip==192.168.2.0/24"}, true},
+		{[]string{"node.ip!=192.168.2.0/24"}, false},
+		{[]string{"node.ip==192.168.2.*"}, true},
+		{[]string{"node.platform.os in (windows, linux)"}, true},
+		{[]string{"node.platform.os notin (windows, linux)"}, false},
+		{[]string{"node.platform.arch==x86_64"}, true},
+		{[]string{"node.labels.zone==zone-1"}, true},
+		{[]string{"node.labels.zone in (zone-2, zone-3)"}, false},
+		{[]string{"node.labels.disk"}, true},
+		{[]string{"!node.labels.disk"}, false},
+		{[]string{"!node.labels.gpu"}, true},
+		{[]string{"node.labels.gpu"}, false},
+		{[]string{"engine.labels.node==node-*"}, true},
+		{[]string{"engine.labels.node!=node-12"}, false},
+		{[]string{"node.labels.zone==zone-1", "node.hostname==host-12"}, true},
+		{[]string{"node.labels.zone==zone-1", "node.hostname==host-13"}, false},
+		{[]string{"node.role==manager"}, false},
+	}
+
+	for _, test := range tests {
+		constraints, err := ParseConstraints(test.constraints)
+		if err != nil {
+			t.Errorf("ParseConstraints(%q) error %s", test.constraints, err)
+			continue
+		}
+		if match := MatchConstraints(constraints, engine); match != test.match {
+			t.Errorf("MatchConstraints(%q) is %t, expected %t", test.constraints, match, test.match)
+		}
+	}
+}
+
+func TestParseCompareConstraints(t *testing.T) {
+
+	tests := []struct {
+		constraints []string
+		valid       bool
+	}{
+		{[]string{"node.cpus>=4"}, true},
+		{[]string{"node.memory<16g"}, true},
+		{[]string{"node.dockerversion>17.03"}, true},
+		{[]string{"node.appversion<=v1.3.7"}, true},
+		{[]string{"node.labels.rack>2"}, true},
+		{[]string{"engine.labels.version>=1.2.0"}, true},
+		{[]string{"node.hostname>host-1"}, false},
+		{[]string{"node.ip<=192.168.2.1"}, false},
+	}
+
+	for _, test := range tests {
+		_, err := ParseConstraints(test.constraints)
+		if test.valid && err != nil {
+			t.Errorf("ParseConstraints(%q) error %s, expected valid", test.constraints, err)
+		}
+		if !test.valid && err == nil {
+			t.Errorf("ParseConstraints(%q) is valid, expected error", test.constraints)
+		}
+	}
+}
+
+func TestMatchCompareConstraints(t *testing.T) {
+
+	engine := &Engine{
+		Cpus:          8,
+		Memory:        16384,
+		DockerVersion: "17.06.2-ce",
+		AppVersion:    "v1.3.7",
+		NodeLabels:    map[string]string{"rack": "3", "kernel": "4.15.0"},
+		EngineLabels:  map[string]string{"version": "1.10.0"},
+	}
+
+	tests := []struct {
+		constraints []string
+		match       bool
+	}{
+		{[]string{"node.cpus>=8"}, true},
+		{[]string{"node.cpus>8"}, false},
+		{[]string{"node.cpus==8"}, true},
+		{[]string{"node.memory>=16g"}, true},
+		{[]string{"node.memory>16384"}, false},
+		{[]string{"node.memory<32g"}, true},
+		{[]string{"node.memory>16x"}, false},
+		{[]string{"node.dockerversion>17.03"}, true},
+		{[]string{"node.dockerversion>=17.06.2"}, true},
+		{[]string{"node.dockerversion<17.06.2"}, false},
+		{[]string{"node.appversion>=1.3.7"}, true},
+		{[]string{"node.appversion<v1.3.10"}, true},
+		{[]string{"node.labels.rack>2"}, true},
+		{[]string{"node.labels.rack<=2"}, false},
+		{[]string{"node.labels.kernel>=4.9"}, true},
+		{[]string{"node.labels.zone>1"}, false},
+		{[]string{"engine.labels.version>1.9.0"}, true},
+		{[]string{"node.cpus>=4", "node.labels.rack<3"}, false},
+	}
+
+	for _, test := range tests {
+		constraints, err := ParseConstraints(test.constraints)
+		if err != nil {
+			t.Errorf("ParseConstraints(%q) error %s", test.constraints, err)
+			continue
+		}
+		if match := MatchConstraints(constraints, engine); match != test.match {
+			t.Errorf("MatchConstraints(%q) is %t, expected %t", test.constraints, match, test.match)
+		}
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+
+	tests := []struct {
+		v1     string
+		v2     string
+		result int
+		valid  bool
+	}{
+		{"1.3.0", "1.3.0", 0, true},
+		{"1.3", "1.3.0", 0, true},
+		{"v1.3.7", "1.3.7", 0, true},
+		{"1.3.10", "1.3.9", 1, true},
+		{"1.2.9", "1.3.0", -1, true},
+		{"17.06.2-ce", "17.06.1", 1, true},
+		{"17.06.2-ce", "17.06.2", 0, true},
+		{"1.0.0+build.5", "1.0.0", 0, true},
+		{"2", "10", -1, true},
+		{"", "1.0", 0, false},
+		{"1.x", "1.0", 0, false},
+		{"1.0", "latest", 0, false},
+	}
+
+	for _, test := range tests {
+		result, err := compareVersions(test.v1, test.v2)
+		if !test.valid {
+			if err == nil {
+				t.Errorf("compareVersions(%q, %q) is valid, expected error", test.v1, test.v2)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("compareVersions(%q, %q) error %s", test.v1, test.v2, err)
+			continue
+		}
+		if result != test.result {
+			t.Errorf("compareVersions(%q, %q) is %d, expected %d", test.v1, test.v2, result, test.result)
+		}
+	}
+}
@@ -25,6 +25,8 @@ const (
 	delayRemoveInterval = 15 * time.Second
 	// engine refresh loop interval
 	refreshInterval = 45 * time.Second
+	// engine performance samples rolling window size
+	perfWindowSize = 10
 )
 
 // Availability define
@@ -34,16 +36,16 @@ type Availability int
 const (
 	//Active, accept scheduling service allocations and failover at any time.
 	Active Availability = iota
-	//Pasue, pause node scheduling, node assigned services are not affected, and no fault migration occurs.
-	Pasue
-	//Expel, exclude nodes from the cluster and create all services on that node to other nodes with active availability.
+	//Pause, pause node scheduling, no new containers are placed on node, include recovery and migration, node assigned services are not affected.
+	Pause
+	//Drain, same as pause, and migrate all cluster services on that node to other nodes with active availability.
 	Drain
 )
 
 // Engine availability content mapping
 var availabilityText = map[Availability]string{
 	Active: "Active",
-	Pasue:  "Pasue",
+	Pause:  "Pause",
 	Drain:  "Drain",
 }
 
@@ -53,6 +55,18 @@ func GetAvailabilityText(availability Availability) string {
 	return availabilityText[availability]
 }
 
+// ParseAvailability is exported
+// parse a availability text, case insensitive.
+func ParseAvailability(text string) (Availability, error) {
+
+	for availability, value := range availabilityText {
+		if strings.EqualFold(value, strings.TrimSpace(text)) {
+			return availability, nil
+		}
+	}
+	return Active, fmt.Errorf("availability %s invalid, expected Active, Pause or Drain", text)
+}
+
 // EngineState define
 type EngineState int
 
@@ -60,7 +74,7 @@ type EngineState int
 const (
 	//StatePending, engine added to cluster engines pool, but not been validated.
 	StatePending EngineState = iota
-	//StateUnhealthy, engine is unreachable.
+	//StateUnhealthy, engine is registered in discovery, but agent api probe failed.
 	StateUnhealthy
 	//StateHealthy, engine is ready reachable.
 	StateHealthy
@@ -118,15 +132,28 @@ type Engine struct {
 	DockerVersion    string            `json:"DockerVersion"`
 	AvailabilityText string            `json:"AvailabilityText"`
 	StateText        string            `json:"StateText"`
+	OvercommitRatio  float64           `json:"OvercommitRatio"`
+	ReservedCpus     int64             `json:"ReservedCpus"`
+	ReservedMemory   int64             `json:"ReservedMemory"`
 
+	//host performance samples rolling window, the latest sample is the last.
+	Performances []*types.EnginePerformance `json:"Performances"`
+
+	baseOvercommit  int64 //cluster overcommit ratio, overridden by node label.
 	overcommitRatio int64
 	client          *Client
 	removePool      *RemovePool
 	configCache     *ContainersConfigCache
 	containers      map[string]*Container
+	pendings        map[string]*pendingContainer
+	replacings      map[string]bool
+	killings        map[string]bool
+	unmanagedPorts  HostPorts //host ports of running containers which are not cluster containers.
 	stopCh          chan struct{}
 	availability    Availability
 	state           EngineState
+	eventsHandler   ContainerEventHandleFunc
+	eventsWatching  bool
 }
 
 // NewEngine is exported
@@ -156,15 +183,22 @@ func NewEngine(nodeData *types.NodeData, overcommitRatio float64, removeDelay ti
 		OSType:           nodeData.OSType,
 		EngineLabels:     nodeData.MapEngineLabels(),
 		NodeLabels:       map[string]string{},
+		Performances:     []*types.EnginePerformance{},
 		AppVersion:       nodeData.AppVersion,
 		DockerVersion:    nodeData.DockerVersion,
 		AvailabilityText: availabilityText[Active],
 		StateText:        stateText[StatePending],
+		OvercommitRatio:  overcommitRatio,
+		baseOvercommit:   int64(overcommitRatio * 100),
 		overcommitRatio:  int64(overcommitRatio * 100),
 		client:           NewClient(nodeData.APIAddr),
 		removePool:       removePool,
 		configCache:      configCache,
 		containers:       make(map[string]*Container),
+		pendings:         make(map[string]*pendingContainer),
+		replacings:       make(map[string]bool),
+		killings:         make(map[string]bool),
+		unmanagedPorts:   HostPorts{},
 		availability:     Active,
 		state:            StatePending,
 	}, nil
@@ -184,6 +218,7 @@ func (engine *Engine) Open() {
 			engine.ValidateContainers()
 			engine.refreshContainersLoop()
 		}()
+		go engine.watchEventsLoop(engine.stopCh)
 		go engine.removeContainersDelayLoop()
 	}
 	engine.Unlock()
@@ -194,7 +229,7 @@ func (engine *Engine) Open() {
 func (engine *Engine) Close() {
 
 	engine.Lock()
-	if engine.state == StateHealthy {
+	if engine.state == StateHealthy || engine.state == StateUnhealthy {
 		close(engine.stopCh)
 		engine.state = StateDisconnected
 		engine.StateText = stateText[engine.state]
@@ -255,10 +290,55 @@ func (engine *Engine) SetNodeLabelsPairs(labels map[string]string) {
 	if labels != nil {
 		engine.Lock()
 		engine.NodeLabels = labels
+		engine.setResources(labels)
 		engine.Unlock()
 	}
 }
 
+// setResources applies node labels capacity overrides, invalid overrides are ignored.
+func (engine *Engine) setResources(labels map[string]string) {
+
+	resources, err := ParseEngineResources(labels)
+	if err != nil {
+		logger.WARN("[#cluster#] engine %s %s, ignored.", engine.IP, err.Error())
+		resources = &EngineResources{}
+	}
+
+	engine.overcommitRatio = engine.baseOvercommit
+	if resources.hasOvercommit {
+		engine.overcommitRatio = resources.overcommitRatio
+	}
+	engine.OvercommitRatio = float64(engine.overcommitRatio) / 100
+	engine.ReservedCpus = resources.reservedCpus
+	engine.ReservedMemory = resources.reservedMemory
+}
+
+// Availability is exported
+func (engine *Engine) Availability() Availability {
+
+	engine.RLock()
+	defer engine.RUnlock()
+	return engine.availability
+}
+
+// SetAvailability is exported
+func (engine *Engine) SetAvailability(availability Availability) {
+
+	engine.Lock()
+	engine.availability = availability
+	engine.AvailabilityText = availabilityText[availability]
+	engine.Unlock()
+}
+
+// IsActive is exported
+// Determine if the engine accepts new containers, engine is healthy and availability is active
+func (engine *Engine) IsActive() bool {
+
+	engine.RLock()
+	defer engine.RUnlock()
+	return engine.state == StateHealthy && engine.availability == Active
+}
+
 // IsHealthy is exported
 // Determine if the engine is in healthy state
 func (engine *Engine) IsHealthy() bool {
@@ -268,6 +348,15 @@ func (engine *Engine) IsHealthy() bool {
 	return engine.state == StateHealthy
 }
 
+// IsUnhealthy is exported
+// Determine if the engine is in unhealthy state
+func (engine *Engine) IsUnhealthy() bool {
+
+	engine.RLock()
+	defer engine.RUnlock()
+	return engine.state == StateUnhealthy
+}
+
 // IsPending is exported
 // Determine if the engine is in pending state
 func (engine *Engine) IsPending() bool {
@@ -305,6 +394,31 @@ func (engine *Engine) SetState(state EngineState) {
 	engine.Unlock()
 }
 
+// switchState sets engine state to to only if current state is from, return false if state is not switched.
+func (engine *Engine) switchState(from EngineState, to EngineState) bool {
+
+	engine.Lock()
+	defer engine.Unlock()
+	if engine.state != from {
+		return false
+	}
+	engine.state = to
+	engine.StateText = stateText[to]
+	return true
+}
+
+// Ping is exported
+// Probe engine agent api, return error if agent api is unreachable in timeout.
+func (engine *Engine) Ping(timeout time.Duration) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if _, err := engine.client.GetDockerInfoRequest(ctx); err != nil {
+		return fmt.Errorf("engine %s ping error, %s", engine.IP, err)
+	}
+	return nil
+}
+
 // MetaIds is exported
 // Return engine containers all metaids array.
 func (engine *Engine) MetaIds() []string {
@@ -380,6 +494,30 @@ func (engine *Engine) Container(containerid string) *Container {
 	return nil
 }
 
+// HostPorts is exported
+// Return engine running containers bound host ports, unmanaged containers included.
+func (engine *Engine) HostPorts() HostPorts {
+
+	hostPorts := containersHostPorts(engine.Containers(""), "")
+	engine.RLock()
+	hostPorts.Add(engine.unmanagedPorts)
+	for _, pending := range engine.pendings {
+		hostPorts.Add(configHostPorts(pending.config))
+	}
+	engine.RUnlock()
+	return hostPorts
+}
+
+// unmanagedHostPorts returns host ports bound by running unmanaged containers, refreshed by RefreshContainers.
+func (engine *Engine) unmanagedHostPorts() HostPorts {
+
+	engine.RLock()
+	defer engine.RUnlock()
+	hostPorts := HostPorts{}
+	hostPorts.Add(engine.unmanagedPorts)
+	return hostPorts
+}
+
 // UsedMemory is exported
 // Return engine all containers used memory size.
 func (engine *Engine) UsedMemory() int64 {
@@ -389,6 +527,9 @@ func (engine *Engine) UsedMemory() int64 {
 	for _, container := range engine.containers {
 		used += container.Info.HostConfig.Memory
 	}
+	for _, pending := range engine.pendings {
+		used += pending.config.Memory * 1024 * 1024
+	}
 	engine.RUnlock()
 	return used
 }
@@ -402,26 +543,64 @@ func (engine *Engine) UsedCpus() int64 {
 	for _, container := range engine.containers {
 		used += container.Info.HostConfig.CPUShares
 	}
+	for _, pending := range engine.pendings {
+		used += pending.config.CPUShares
+	}
 	engine.RUnlock()
 	return used
 }
 
+// UsageMemory is exported
+// Return engine host actual used memory size, average of performance samples window, 0 if no sample collected.
+func (engine *Engine) UsageMemory() int64 {
+
+	engine.RLock()
+	defer engine.RUnlock()
+	if len(engine.Performances) == 0 {
+		return 0
+	}
+
+	var used int64
+	for _, performance := range engine.Performances {
+		used += performance.MemoryUsage
+	}
+	return used / int64(len(engine.Performances))
+}
+
+// UsageCpus is exported
+// Return engine host actual used cpus size, average cpu percent of performance samples window, 0 if no sample collected.
+func (engine *Engine) UsageCpus() int64 {
+
+	engine.RLock()
+	defer engine.RUnlock()
+	if len(engine.Performances) == 0 {
+		return 0
+	}
+
+	var percent float64
+	for _, performance := range engine.Performances {
+		percent += performance.CPUPercent
+	}
+	percent = percent / float64(len(engine.Performances))
+	return int64(math.Ceil(percent * float64(engine.Cpus) / 100))
+}
+
 // TotalMemory is exported
-// Return engine total memory size.
+// Return engine total memory size, reserved memory is subtracted and overcommit ratio is applied.
 func (engine *Engine) TotalMemory() int64 {
 
 	engine.RLock()
 	defer engine.RUnlock()
-	return engine.Memory + (engine.Memory * engine.overcommitRatio / 100)
+	return engineCapacity(engine.Memory, engine.ReservedMemory, engine.overcommitRatio)
 }
 
 // TotalCpus is exported
-// Return engine total cpus size.
+// Return engine total cpus size, reserved cpus are subtracted and overcommit ratio is applied.
 func (engine *Engine) TotalCpus() int64 {
 
 	engine.RLock()
 	defer engine.RUnlock()
-	return engine.Cpus + (engine.Cpus * engine.overcommitRatio / 100)
+	return engineCapacity(engine.Cpus, engine.ReservedCpus, engine.overcommitRatio)
 }
 
 // CreateContainer is exported
@@ -440,7 +619,7 @@ func (engine *Engine) CreateContainer(config models.Container) (*Container, erro
 	if metaData == nil {
 		return nil, ErrClusterMetaDataNotFound
 	}
-	baseConfig := &ContainerBaseConfig{Index: containerIndex, Container: config, MetaData: metaData}
+	baseConfig := &ContainerBaseConfig{Index: containerIndex, Container: config, DynamicPorts: dynamicPortBindings(metaData, config), MetaData: metaData}
 	engine.configCache.CreateContainerBaseConfig(metaData.MetaID, baseConfig)
 	logger.INFO("[#cluster#] engine %s create container %s:%s", engine.IP, ShortContainerID(createContainerResponse.ID), config.Name)
 	containers, err := engine.updateContainer(createContainerResponse.ID, engine.containers)
@@ -555,6 +734,9 @@ func (engine *Engine) ForceUpgradeContainer(operate models.ContainerOperate) (*C
 	containerConfig.Name = container.Config.Name
 	containerConfig.Image = container.Config.Image[0:tagIndex] + ":" + operate.ImageTag
 	containerConfig.Env = container.BaseConfig.Env
+	if metaData.Placement.DynamicPorts { //keep allocated dynamic ports.
+		containerConfig.Ports = container.BaseConfig.Ports
+	}
 	if err := engine.RemoveContainer(operate.Container); err != nil {
 		logger.WARN("[#cluster#] engine %s upgrading, remove original container %s failure.", engine.IP, ShortContainerID(operate.Container))
 	}
@@ -632,8 +814,23 @@ func (engine *Engine) RefreshContainers() error {
 		}
 	}
 
+	//unmanaged containers are not engine containers, their published host ports are kept for scheduling.
+	//host network ports of unmanaged containers are not listed by agent, events of them are applied at next refresh.
+	unmanagedPorts := HostPorts{}
+	for _, container := range containers {
+		if _, ret := merged[container.ID]; ret || (container.State != "running" && container.State != "restarting") {
+			continue
+		}
+		for _, port := range container.Ports {
+			if port.PublicPort != 0 {
+				unmanagedPorts[hostPortKey(port.Type, int(port.PublicPort))] = true
+			}
+		}
+	}
+
 	engine.Lock()
 	engine.containers = merged
+	engine.unmanagedPorts = unmanagedPorts
 	engine.Unlock()
 	return nil
 }
@@ -681,9 +878,13 @@ func (engine *Engine) refreshContainersLoop() {
 	//engine performance collection interval
 	const perfUpdateInterval = 5 * time.Minute
 	lastPrefUpdateAt := seedAt
+	//agent doesn't serve performance, collection stops until engine is opened again.
+	perfSupported := true
 	//engine validate containers interval
 	const doValidateInterval = 15 * time.Minute
 	lastValidateAt := seedAt
+	//engine full refresh containers time, events watching refresh is reconciliation only.
+	lastRefreshAt := seedAt
 
 	for {
 		runTicker := time.NewTicker(refreshInterval)
@@ -693,12 +894,20 @@ func (engine *Engine) refreshContainersLoop() {
 				runTicker.Stop()
 				if engine.IsHealthy() {
 					currentAt := time.Now()
-					if time.Since(lastPrefUpdateAt) > perfUpdateInterval {
-						//engine.updatePerformance()
+					if perfSupported && time.Since(lastPrefUpdateAt) > perfUpdateInterval {
+						if err := engine.updatePerformance(); err == ErrClusterEngineAPINotSupported {
+							perfSupported = false
+							logger.INFO("[#cluster#] engine %s agent doesn't serve performance, engine is weighted by containers resources.", engine.IP)
+						} else if err != nil {
+							logger.WARN("[#cluster#] engine %s update performance error:%s", engine.IP, err.Error())
+						}
 						lastPrefUpdateAt = currentAt
 					}
-					if err := engine.RefreshContainers(); err != nil {
-						logger.ERROR("[#cluster#] engine %s refresh containers error:%s", engine.IP, err.Error())
+					if !engine.IsEventsWatching() || time.Since(lastRefreshAt) > reconcileInterval {
+						if err := engine.RefreshContainers(); err != nil {
+							logger.ERROR("[#cluster#] engine %s refresh containers error:%s", engine.IP, err.Error())
+						}
+						lastRefreshAt = currentAt
 					}
 					if time.Since(lastValidateAt) > doValidateInterval {
 						engine.ValidateContainers()
@@ -761,7 +970,7 @@ func (engine *Engine) useRemovePool(containerConfig *ContainerConfig) bool {
 		return false
 	}
 
-	if containerConfig.NetworkMode != "bridge" && containerConfig.NetworkMode != "nat" {
+	if !isBridgeNetwork(containerConfig.Container) {
 		return false
 	}
 
@@ -771,14 +980,34 @@ func (engine *Engine) useRemovePool(containerConfig *ContainerConfig) bool {
 		return false
 	}
 
-	for _, portBindings := range metaData.Config.Ports {
-		if portBindings.PublicPort != 0 {
-			return false
-		}
+	//delay removed container still binds host ports.
+	if isBindHostPorts(metaData.Config) {
+		return false
 	}
 	return metaData.IsRemoveDelay
 }
 
+// updatePerformance exported
+// collect host performance sample from agent, keep the latest perfWindowSize samples.
+func (engine *Engine) updatePerformance() error {
+
+	performance, err := engine.client.GetPerformanceRequest(context.Background())
+	if err != nil {
+		return err
+	}
+
+	performance.Timestamp = time.Now().Unix()
+	engine.Lock()
+	performances := append([]*types.EnginePerformance{}, engine.Performances...)
+	performances = append(performances, performance)
+	if len(performances) > perfWindowSize {
+		performances = performances[len(performances)-perfWindowSize:]
+	}
+	engine.Performances = performances
+	engine.Unlock()
+	return nil
+}
+
 // updateSpecs exported
 func (engine *Engine) updateSpecs() error {
 
@@ -839,3 +1068,139 @@ func (engine *Engine) updateContainer(containerid string, containers map[string]
 	}
 	return containers, nil
 }
+
+// pendingContainer is a container which is being created on engine,
+// it is counted by scheduling until created container is in engine containers.
+type pendingContainer struct {
+	metaid string
+	config models.Container
+}
+
+// ScheduledContainers is exported
+// Return count of meta containers on engine for scheduling,
+// containers which are being created are counted, containers which are being replaced are not.
+func (engine *Engine) ScheduledContainers(metaid string) int {
+
+	containers := engine.Containers(metaid)
+	engine.RLock()
+	defer engine.RUnlock()
+	count := 0
+	for _, container := range containers {
+		if !engine.replacings[container.Info.ID] {
+			count = count + 1
+		}
+	}
+	for _, pending := range engine.pendings {
+		if pending.metaid == metaid {
+			count = count + 1
+		}
+	}
+	return count
+}
+
+// reservePending reserves engine for a container which is being created, config name is unique of cluster containers.
+func (engine *Engine) reservePending(metaid string, config models.Container) {
+
+	engine.Lock()
+	engine.pendings[config.Name] = &pendingContainer{metaid: metaid, config: config}
+	engine.Unlock()
+}
+
+// releasePending releases engine reservation of a created or failed container.
+func (engine *Engine) releasePending(name string) {
+
+	engine.Lock()
+	delete(engine.pendings, name)
+	engine.Unlock()
+}
+
+// snapshot returns a copy of engine for scheduling simulation, it has no client and is never opened.
+// containers and reservations are copied, so snapshot can be changed without affecting engine.
+func (engine *Engine) snapshot() *Engine {
+
+	engine.RLock()
+	defer engine.RUnlock()
+	snapshot := &Engine{
+		ID:               engine.ID,
+		Name:             engine.Name,
+		IP:               engine.IP,
+		APIAddr:          engine.APIAddr,
+		Cpus:             engine.Cpus,
+		Memory:           engine.Memory,
+		StorageDirver:    engine.StorageDirver,
+		KernelVersion:    engine.KernelVersion,
+		Architecture:     engine.Architecture,
+		OperatingSystem:  engine.OperatingSystem,
+		OSType:           engine.OSType,
+		EngineLabels:     engine.EngineLabels,
+		NodeLabels:       engine.NodeLabels,
+		AppVersion:       engine.AppVersion,
+		DockerVersion:    engine.DockerVersion,
+		AvailabilityText: engine.AvailabilityText,
+		StateText:        engine.StateText,
+		OvercommitRatio:  engine.OvercommitRatio,
+		ReservedCpus:     engine.ReservedCpus,
+		ReservedMemory:   engine.ReservedMemory,
+		Performances:     engine.Performances,
+		baseOvercommit:   engine.baseOvercommit,
+		overcommitRatio:  engine.overcommitRatio,
+		configCache:      engine.configCache,
+		containers:       make(map[string]*Container),
+		pendings:         make(map[string]*pendingContainer),
+		replacings:       make(map[string]bool),
+		unmanagedPorts:   engine.unmanagedPorts,
+		availability:     engine.availability,
+		state:            engine.state,
+	}
+
+	for id, container := range engine.containers {
+		snapshot.containers[id] = container
+	}
+	for name, pending := range engine.pendings {
+		snapshot.pendings[name] = pending
+	}
+	for id, replacing := range engine.replacings {
+		snapshot.replacings[id] = replacing
+	}
+	return snapshot
+}
+
+// removeSnapshotContainer removes a container of engine snapshot, the container is not removed from engine host.
+func (engine *Engine) removeSnapshotContainer(containerid string) {
+
+	engine.Lock()
+	delete(engine.containers, containerid)
+	engine.Unlock()
+}
+
+// reserveReplacing marks a container which is being replaced by a new container, it isn't counted by scheduling.
+func (engine *Engine) reserveReplacing(containerid string) {
+
+	engine.Lock()
+	engine.replacings[containerid] = true
+	engine.Unlock()
+}
+
+// releaseReplacing unmarks a replaced container, after new container is created or failed.
+func (engine *Engine) releaseReplacing(containerid string) {
+
+	engine.Lock()
+	delete(engine.replacings, containerid)
+	engine.Unlock()
+}
+
+// delayRemoveContainer puts a container which engine failed to remove into remove pool, removing is retried by remove-delay loop.
+func (engine *Engine) delayRemoveContainer(metaid string, containerid string) {
+
+	engine.removePool.Lock()
+	if _, ret := engine.removePool.containers[containerid]; !ret {
+		engine.removePool.containers[containerid] = &RemoveContainer{
+			metaID:      metaid,
+			containerID: containerid,
+			timeStamp:   time.Now().Add(-engine.removePool.removeDelay).Unix(),
+			failCount:   0,
+		}
+		logger.INFO("[#cluster#] engine %s container %s add to remove-delay pool.", engine.IP, ShortContainerID(containerid))
+	}
+	engine.removePool.Unlock()
+}
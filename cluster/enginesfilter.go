@@ -9,6 +9,8 @@ type EnginesFilter struct {
 	sync.RWMutex
 	allocEngines map[string]*Engine
 	failEngines  map[string]*Engine
+	rejects      map[string]string
+	limiter      *EnginesLimiter
 }
 
 // NewEnginesFilter is exported
@@ -17,6 +19,7 @@ func NewEnginesFilter() *EnginesFilter {
 	return &EnginesFilter{
 		allocEngines: make(map[string]*Engine),
 		failEngines:  make(map[string]*Engine),
+		rejects:      make(map[string]string),
 	}
 }
 
@@ -102,3 +105,120 @@ func (filter *EnginesFilter) Filter(engines []*Engine) []*Engine {
 	filter.RUnlock()
 	return out
 }
+
+// FilterFailEngines is exported
+// return engines which creates of filter didn't fail on.
+func (filter *EnginesFilter) FilterFailEngines(engines []*Engine) []*Engine {
+
+	filter.RLock()
+	defer filter.RUnlock()
+	out := []*Engine{}
+	for _, engine := range engines {
+		if _, ret := filter.failEngines[engine.IP]; !ret {
+			out = append(out, engine)
+		}
+	}
+	return out
+}
+
+// Reject is exported
+// record the reason engine is rejected by engines selection, the last reason of engine is kept.
+// nil filter records nothing.
+func (filter *EnginesFilter) Reject(engine *Engine, reason string) {
+
+	if filter == nil {
+		return
+	}
+
+	filter.Lock()
+	filter.rejects[engine.IP] = reason
+	filter.Unlock()
+}
+
+// Rejects is exported
+// return rejected reasons of engines recorded since last call, key is engine ip.
+func (filter *EnginesFilter) Rejects() map[string]string {
+
+	filter.Lock()
+	defer filter.Unlock()
+	rejects := filter.rejects
+	filter.rejects = make(map[string]string)
+	return rejects
+}
+
+// SetLimiter is exported
+// set concurrent creates limiter of engines, nil is unlimited.
+func (filter *EnginesFilter) SetLimiter(limiter *EnginesLimiter) {
+
+	filter.Lock()
+	filter.limiter = limiter
+	filter.Unlock()
+}
+
+// AcquireEngine is exported
+// acquire a create slot of engine from limiter, wait until engine is not busy.
+func (filter *EnginesFilter) AcquireEngine(engine *Engine) {
+
+	filter.RLock()
+	limiter := filter.limiter
+	filter.RUnlock()
+	if limiter != nil {
+		limiter.Acquire(engine)
+	}
+}
+
+// ReleaseEngine is exported
+func (filter *EnginesFilter) ReleaseEngine(engine *Engine) {
+
+	filter.RLock()
+	limiter := filter.limiter
+	filter.RUnlock()
+	if limiter != nil {
+		limiter.Release(engine)
+	}
+}
+
+// EnginesLimiter is exported
+// limit concurrent creates of every engine, limit 0 is unlimited.
+type EnginesLimiter struct {
+	sync.Mutex
+	cond   *sync.Cond
+	limit  int
+	counts map[string]int
+}
+
+// NewEnginesLimiter is exported
+func NewEnginesLimiter(limit int) *EnginesLimiter {
+
+	limiter := &EnginesLimiter{
+		limit:  limit,
+		counts: make(map[string]int),
+	}
+	limiter.cond = sync.NewCond(limiter)
+	return limiter
+}
+
+// Acquire is exported
+// wait until engine creates are less than limit, then take a create slot of engine.
+func (limiter *EnginesLimiter) Acquire(engine *Engine) {
+
+	limiter.Lock()
+	defer limiter.Unlock()
+	for limiter.limit > 0 && limiter.counts[engine.IP] >= limiter.limit {
+		limiter.cond.Wait()
+	}
+	limiter.counts[engine.IP] = limiter.counts[engine.IP] + 1
+}
+
+// Release is exported
+func (limiter *EnginesLimiter) Release(engine *Engine) {
+
+	limiter.Lock()
+	defer limiter.Unlock()
+	if count := limiter.counts[engine.IP] - 1; count > 0 {
+		limiter.counts[engine.IP] = count
+	} else {
+		delete(limiter.counts, engine.IP)
+	}
+	limiter.cond.Broadcast()
+}
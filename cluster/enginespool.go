@@ -49,11 +49,15 @@ func (pool *EnginesPool) Release() {
 }
 
 // InitEngineNodeLabels is exported
+// init engine node labels and availability from node storage.
 func (pool *EnginesPool) InitEngineNodeLabels(engine *Engine) {
 
 	node, _ := pool.Cluster.storageDriver.NodeStorage.NodeByIP(engine.IP)
 	if node != nil {
 		engine.SetNodeLabelsPairs(node.NodeLabels)
+		if availability, err := ParseAvailability(node.Availability); err == nil {
+			engine.SetAvailability(availability)
+		}
 	}
 }
 
@@ -106,6 +110,7 @@ func (pool *EnginesPool) AddEngine(ip string, name string) {
 		if err != nil {
 			return
 		}
+		poolEngine.SetEventsHandler(pool.Cluster.containerEventHandleFunc)
 		pool.poolEngines[poolEngine.IP] = poolEngine
 		logger.INFO("[#cluster#] addengine, pool engine create %s %s %s.", poolEngine.IP, poolEngine.Name, poolEngine.State())
 	}
@@ -152,14 +157,19 @@ func (pool *EnginesPool) doLoop() {
 								pool.Cluster.Lock()
 								pool.Cluster.engines[engine.IP] = engine
 								pool.Cluster.Unlock()
+								if engine.Availability() == Drain { //resume draining engine.
+									pool.Cluster.migtatorCache.Drain(engine)
+								}
 								logger.INFO("[#cluster#] engine %s %s %s", engine.IP, engine.Name, engine.State())
 							}
 							wgroup.Done()
 						}(pendEngine)
-					} else if pendEngine.IsHealthy() {
+					} else if pendEngine.IsHealthy() || pendEngine.IsUnhealthy() {
 						wgroup.Add(1)
 						go func(engine *Engine) {
-							pool.Cluster.migtatorCache.Start(engine)
+							if migrated := pool.Cluster.enginesProber.Remove(engine); !migrated {
+								pool.Cluster.migtatorCache.Start(engine)
+							}
 							engine.Close()
 							logger.INFO("[#cluster#] engine %s %s %s", engine.IP, engine.Name, engine.State())
 							wgroup.Done()
@@ -14,8 +14,32 @@ var (
 	ErrClusterContainerNotFound = errors.New("cluster container not found")
 	//cluster server not found
 	ErrClusterServerNotFound = errors.New("cluster server not found")
+	//cluster server node labels invalid
+	ErrClusterNodeLabelsInvalid = errors.New("cluster server node labels invalid, reserved resources labels values are invalid")
+	//cluster server availability invalid
+	ErrClusterAvailabilityInvalid = errors.New("cluster server availability invalid, expected Active, Pause or Drain")
 	//cluster group no docker engine available
 	ErrClusterNoEngineAvailable = errors.New("cluster no docker-engine available")
+	//cluster group no docker engine matches placement platforms
+	ErrClusterNoPlatformEngineAvailable = errors.New("cluster no docker-engine matches placement platforms")
+	//cluster group no docker engine available under placement max instances per engine
+	ErrClusterMaxPerEngineExceeded = errors.New("cluster no docker-engine available, placement max instances per engine exceeded")
+	//cluster group no docker engine available without host ports conflict
+	ErrClusterHostPortsConflict = errors.New("cluster no docker-engine available, host ports are already bound")
+	//cluster engine no free host port in placement port range
+	ErrClusterDynamicPortsExhausted = errors.New("cluster docker-engine no free host port in placement port range")
+	//cluster placement port range invalid
+	ErrClusterPortRangeInvalid = errors.New("cluster placement port range invalid, expected start-end")
+	//cluster placement reduce policy invalid
+	ErrClusterReducePolicyInvalid = errors.New("cluster placement reduce policy invalid, expected index, newest, oldest, unhealthy or spread")
+	//cluster placement affinities invalid
+	ErrClusterAffinitiesInvalid = errors.New("cluster placement affinities invalid, expected affinity or anti-affinity of a meta name or meta label")
+	//cluster placement strategy invalid
+	ErrClusterStrategyInvalid = errors.New("cluster placement strategy invalid, expected spread, binpack or random")
+	//cluster group quota invalid
+	ErrClusterGroupQuotaInvalid = errors.New("cluster group quota invalid, should be larger than or equal to 0")
+	//cluster group quota exceeded
+	ErrClusterGroupQuotaExceeded = errors.New("cluster group quota exceeded")
 	//cluster containers instances invalid
 	ErrClusterContainersInstancesInvalid = errors.New("cluster containers instances invalid")
 	//cluster containers meta create failure
@@ -30,8 +54,18 @@ var (
 	ErrClusterContainersUpgrading = errors.New("cluster containers state is upgrading")
 	//cluster containers is migrating
 	ErrClusterContainersMigrating = errors.New("cluster containers state is migrating")
-	//cluster containers is setting
-	ErrClusterContainersSetting = errors.New("cluster containers state is setting")
+	//cluster operation not found
+	ErrClusterOperationNotFound = errors.New("cluster operation not found")
+	//cluster preempt victim containers meta has running operations
+	ErrClusterPreemptVictimBusy = errors.New("cluster preempt victim containers meta is busy")
+	//cluster docker-engine agent doesn't serve the api
+	ErrClusterEngineAPINotSupported = errors.New("cluster docker-engine agent api not supported")
+	//cluster meta migrate policy invalid
+	ErrClusterMigratePolicyInvalid = errors.New("cluster migrate policy invalid, expected auto, manual or never mode and a valid delay")
+	//cluster rolling upgrade invalid
+	ErrClusterRollingUpgradeInvalid = errors.New("cluster rolling upgrade invalid, expected batch size, max unavailable and max surge are not negative and a valid delay")
+	//cluster migration not found
+	ErrClusterMigrationNotFound = errors.New("cluster migration not found")
 	//cluster containers instances no change
 	ErrClusterContainersInstancesNoChange = errors.New("cluster containers instances no change")
 )
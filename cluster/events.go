@@ -0,0 +1,295 @@
+package cluster
+
+import "github.com/humpback/humpback-center/cluster/types"
+import "github.com/humpback/common/models"
+import "github.com/humpback/gounits/logger"
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+const (
+	// engine events long-poll wait duration
+	eventsPollWait = 30 * time.Second
+	// engine events poll retry interval after poll failed, doubled after every failure.
+	eventsRetryInterval = 5 * time.Second
+	// engine events poll max retry interval
+	eventsRetryMaxInterval = 5 * time.Minute
+	// engine events watching stops after agent doesn't serve events for times
+	eventsNotSupportedThreshold = 3
+	// engine full refresh containers interval when events are watching, reconcile missed events.
+	reconcileInterval = 5 * time.Minute
+	// dead container which ran less than uptime isn't started again, a crash looping container stays dead.
+	deadStartMinUptime = 10 * time.Second
+)
+
+// container event actions which change engine container info.
+var containerUpdateActions = []string{
+	"create", "start", "restart", "die", "kill", "stop", "pause", "unpause", "rename", "update", "oom", "health_status",
+}
+
+// ContainerEventHandleFunc is exported
+// engine container event handle func, container is engine container of event, nil if container is unknown.
+type ContainerEventHandleFunc func(engine *Engine, event *types.ContainerEvent, container *Container)
+
+// SetEventsHandler is exported
+func (engine *Engine) SetEventsHandler(handler ContainerEventHandleFunc) {
+
+	engine.Lock()
+	engine.eventsHandler = handler
+	engine.Unlock()
+}
+
+// IsEventsWatching is exported
+// Determine if the engine containers are synced by events, full refresh is reconciliation only.
+func (engine *Engine) IsEventsWatching() bool {
+
+	engine.RLock()
+	defer engine.RUnlock()
+	return engine.eventsWatching
+}
+
+func (engine *Engine) setEventsWatching(watching bool) {
+
+	engine.Lock()
+	changed := engine.eventsWatching != watching
+	engine.eventsWatching = watching
+	engine.Unlock()
+	if changed {
+		logger.INFO("[#cluster#] engine %s events watching %t.", engine.IP, watching)
+	}
+}
+
+// watchEventsLoop long-polls agent container events, and applies events to engine containers incrementally.
+// events are polled from the last poll until, so events are not missed between two polls.
+// failed polls are retried with backoff, watching stops if agent doesn't serve events, containers are synced by refresh only.
+func (engine *Engine) watchEventsLoop(stopCh chan struct{}) {
+
+	since := time.Now().Unix()
+	retryInterval := eventsRetryInterval
+	notSupported := 0
+	for {
+		until := time.Now().Add(eventsPollWait).Unix()
+		ctx, cancel := context.WithTimeout(context.Background(), eventsPollWait+eventsRetryInterval*3)
+		go func() {
+			select {
+			case <-stopCh:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+
+		events, err := engine.client.GetEventsRequest(ctx, since, until)
+		cancel()
+		select {
+		case <-stopCh:
+			engine.setEventsWatching(false)
+			return
+		default:
+		}
+
+		if err != nil {
+			engine.setEventsWatching(false)
+			if err == ErrClusterEngineAPINotSupported {
+				if notSupported = notSupported + 1; notSupported >= eventsNotSupportedThreshold {
+					logger.WARN("[#cluster#] engine %s agent doesn't serve events, stop watching events.", engine.IP)
+					return
+				}
+			} else {
+				notSupported = 0
+			}
+			logger.WARN("[#cluster#] engine %s watch events error:%s, retry after %s.", engine.IP, err.Error(), retryInterval)
+			retryTicker := time.NewTicker(retryInterval)
+			select {
+			case <-retryTicker.C:
+				retryTicker.Stop()
+			case <-stopCh:
+				retryTicker.Stop()
+				return
+			}
+			if retryInterval = retryInterval * 2; retryInterval > eventsRetryMaxInterval {
+				retryInterval = eventsRetryMaxInterval
+			}
+			continue
+		}
+
+		retryInterval = eventsRetryInterval
+		notSupported = 0
+		engine.setEventsWatching(true)
+		for _, event := range events {
+			engine.handleContainerEvent(event)
+		}
+		since = until
+	}
+}
+
+// handleContainerEvent applies a container event to engine containers.
+// destroy event removes container, update events refresh container info.
+func (engine *Engine) handleContainerEvent(event *types.ContainerEvent) {
+
+	action := event.Action
+	if action == "" {
+		action = event.Status
+	}
+
+	if event.ID == "" || (event.Type != "" && event.Type != "container") {
+		return
+	}
+
+	var container *Container
+	if action == "destroy" {
+		engine.Lock()
+		if current, ret := engine.containers[event.ID]; ret {
+			container = current
+			delete(engine.containers, event.ID)
+		}
+		delete(engine.killings, event.ID)
+		engine.Unlock()
+	} else {
+		matched := false
+		for _, updateAction := range containerUpdateActions {
+			if strings.HasPrefix(action, updateAction) {
+				matched = true
+				break
+			}
+		}
+
+		if !matched {
+			return
+		}
+
+		//docker stop and kill send kill event before die, the container is stopped on purpose.
+		if strings.HasPrefix(action, "kill") {
+			engine.Lock()
+			engine.killings[event.ID] = true
+			engine.Unlock()
+		}
+
+		engine.RLock()
+		containers := engine.containers
+		engine.RUnlock()
+		if _, err := engine.updateContainer(event.ID, containers); err != nil {
+			logger.WARN("[#cluster#] engine %s container %s event %s, %s", engine.IP, ShortContainerID(event.ID), action, err.Error())
+			return
+		}
+		container = engine.Container(event.ID)
+	}
+
+	engine.RLock()
+	handler := engine.eventsHandler
+	engine.RUnlock()
+	if handler != nil {
+		handler(engine, event, container)
+	}
+}
+
+// takeKilling returns true if container was killed before it died, and clears the kill mark.
+func (engine *Engine) takeKilling(containerid string) bool {
+
+	engine.Lock()
+	defer engine.Unlock()
+	killed := engine.killings[containerid]
+	delete(engine.killings, containerid)
+	return killed
+}
+
+// containerEventHandleFunc recovers meta containers without waiting recovery interval,
+// when a meta container is destroyed out of cluster, or dies and isn't restarted by its restart policy.
+// a dead container is still on engine and recovery re-creates missing containers only, so it is started again in a recovery operation.
+// a container which is killed or stopped on purpose, ran less than deadStartMinUptime, or is restarted by docker isn't started.
+func (cluster *Cluster) containerEventHandleFunc(engine *Engine, event *types.ContainerEvent, container *Container) {
+
+	action := event.Action
+	if action == "" {
+		action = event.Status
+	}
+
+	if action == "die" && engine.takeKilling(event.ID) {
+		return
+	}
+
+	if container == nil || (action != "destroy" && action != "die") {
+		return
+	}
+
+	metaid := container.MetaID()
+	if metaid == "" {
+		return
+	}
+
+	metaData := cluster.GetMetaData(metaid)
+	if metaData == nil || !metaData.IsRecovery {
+		return
+	}
+
+	if cluster.operationsQueue.Busy(metaData.GroupID, metaData.Config.Name, metaData.MetaID) {
+		return //container is destroyed or stopped by meta operation.
+	}
+
+	if action == "die" {
+		if !isDeadContainerStartable(container) {
+			return
+		}
+		logger.INFO("[#cluster#] engine %s meta %s container %s died, restart policy doesn't restart it, start.", engine.IP, metaid, ShortContainerID(event.ID))
+		go cluster.startDeadContainer(engine, metaData, event.ID)
+		return
+	}
+
+	logger.INFO("[#cluster#] engine %s meta %s container %s destroyed, recovery.", engine.IP, metaid, ShortContainerID(event.ID))
+	go func() {
+		if err := cluster.RecoveryContainers(metaid); err != nil {
+			logger.WARN("[#cluster#] recovery containers error, %s", err.Error())
+		}
+	}()
+}
+
+// isDeadContainerStartable returns true if docker doesn't restart the dead container by restart policy,
+// and container ran at least deadStartMinUptime. on-failure policy which reached its retry count keeps container dead.
+func isDeadContainerStartable(container *Container) bool {
+
+	if container.Config == nil || container.Info.ContainerJSONBase == nil || container.Info.State == nil {
+		return false
+	}
+
+	state := container.Info.State
+	if state.Running || state.Restarting {
+		return false
+	}
+
+	switch strings.ToLower(strings.TrimSpace(container.Config.RestartPolicy)) {
+	case "", "no":
+	default: //always, unless-stopped and on-failure are restarted by docker or give up on purpose.
+		return false
+	}
+
+	startedAt, err := time.Parse(time.RFC3339Nano, state.StartedAt)
+	if err != nil {
+		return false
+	}
+	finishedAt, err := time.Parse(time.RFC3339Nano, state.FinishedAt)
+	if err != nil {
+		return false
+	}
+	return finishedAt.Sub(startedAt) >= deadStartMinUptime
+}
+
+// startDeadContainer starts a dead meta container in a recovery operation of meta, after prior operations of meta.
+func (cluster *Cluster) startDeadContainer(engine *Engine, metaData *MetaData, containerid string) {
+
+	operation := cluster.submitOperation(metaData, RecoveryOperation, func(operation *Operation) (interface{}, error) {
+		container := engine.Container(containerid)
+		if container == nil || container.Info.ContainerJSONBase == nil || container.Info.State == nil {
+			return nil, nil //container is removed by a prior operation.
+		}
+		if container.Info.State.Running || container.Info.State.Restarting {
+			return nil, nil
+		}
+		return nil, engine.OperateContainer(models.ContainerOperate{Action: "start", Container: containerid})
+	})
+
+	if _, err := operation.Wait(); err != nil {
+		logger.WARN("[#cluster#] engine %s start dead container %s error, %s", engine.IP, ShortContainerID(containerid), err.Error())
+	}
+}
@@ -24,6 +24,9 @@ const (
 	UpgradeMetaEvent
 	MigrateMetaEvent
 	RecoveryMetaEvent
+	PreemptMetaEvent
+	EvictMetaEvent
+	RebalanceMetaEvent
 )
 
 func (event HookEvent) String() string {
@@ -43,15 +46,22 @@ func (event HookEvent) String() string {
 		return "MigrateMetaEvent"
 	case RecoveryMetaEvent:
 		return "RecoveryMetaEvent"
+	case PreemptMetaEvent:
+		return "PreemptMetaEvent"
+	case EvictMetaEvent:
+		return "EvictMetaEvent"
+	case RebalanceMetaEvent:
+		return "RebalanceMetaEvent"
 	}
 	return ""
 }
 
 // HookContainer is exported
 type HookContainer struct {
-	IP        string           `json:"IP"`
-	Name      string           `json:"Name"`
-	Container models.Container `json:"Container"`
+	IP           string               `json:"IP"`
+	Name         string               `json:"Name"`
+	Container    models.Container     `json:"Container"`
+	DynamicPorts []models.PortBinding `json:"DynamicPorts"`
 }
 
 // HookContainers is exported
@@ -1,9 +1,12 @@
 package cluster
 
+import "github.com/humpback/humpback-center/cluster/storage/entry"
+import "github.com/humpback/humpback-center/cluster/types"
 import "github.com/humpback/gounits/logger"
 
 import (
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 )
@@ -18,6 +21,57 @@ const (
 	MigrateCompleted
 )
 
+const (
+	// AutoMigratePolicy is exported
+	AutoMigratePolicy = "auto"
+	// ManualMigratePolicy is exported
+	ManualMigratePolicy = "manual"
+	// NeverMigratePolicy is exported
+	NeverMigratePolicy = "never"
+)
+
+// migrateCanceledText is the outcome of a migrate container which is canceled.
+const migrateCanceledText = "MigrateCanceled"
+
+// migrationRetention is how long finished migrations are kept in storage.
+var migrationRetention = time.Duration(time.Hour * 24)
+
+// ValidateMigratePolicy is exported
+// empty mode is auto, empty delay is cluster migrate delay.
+func ValidateMigratePolicy(policy types.MigratePolicy) error {
+
+	switch strings.ToLower(strings.TrimSpace(policy.Mode)) {
+	case "", AutoMigratePolicy, ManualMigratePolicy, NeverMigratePolicy:
+	default:
+		return ErrClusterMigratePolicyInvalid
+	}
+
+	if delay := strings.TrimSpace(policy.Delay); delay != "" {
+		if dur, err := time.ParseDuration(delay); err != nil || dur < 0 {
+			return ErrClusterMigratePolicyInvalid
+		}
+	}
+	return nil
+}
+
+// migratePolicy returns migrate policy mode and offline engine migrate delay of meta.
+func migratePolicy(metaData *MetaData, migrateDelay time.Duration) (string, time.Duration) {
+
+	if metaData == nil {
+		return AutoMigratePolicy, migrateDelay
+	}
+
+	mode := strings.ToLower(strings.TrimSpace(metaData.MigratePolicy.Mode))
+	if mode == "" {
+		mode = AutoMigratePolicy
+	}
+
+	if dur, err := time.ParseDuration(strings.TrimSpace(metaData.MigratePolicy.Delay)); err == nil && dur >= 0 {
+		migrateDelay = dur
+	}
+	return mode, migrateDelay
+}
+
 func (state MigrateState) String() string {
 
 	switch state {
@@ -36,11 +90,16 @@ func (state MigrateState) String() string {
 // MigrateContainer is exported
 type MigrateContainer struct {
 	sync.RWMutex
-	ID         string
-	metaData   *MetaData
-	baseConfig *ContainerBaseConfig
-	filter     *EnginesFilter
-	state      MigrateState
+	ID             string
+	metaData       *MetaData
+	baseConfig     *ContainerBaseConfig
+	filter         *EnginesFilter
+	state          MigrateState
+	retries        int
+	sourceIP       string
+	engineIP       string
+	newContainerID string
+	err            string
 }
 
 // NewMigrateContainer is exported
@@ -79,29 +138,43 @@ func (mContainer *MigrateContainer) SetState(state MigrateState) {
 func (mContainer *MigrateContainer) Execute(cluster *Cluster) {
 
 	mContainer.SetState(Migrating)
-	mContainer.baseConfig.ID = "" //re-create a new container
-	engine, container, err := cluster.createContainer(mContainer.metaData, mContainer.filter, nil, mContainer.baseConfig.Container)
+	config := mContainer.baseConfig.Container
+	config.ID = "" //re-create a new container, original base config is kept until container migrated.
+	engine, container, err := cluster.createContainer(mContainer.metaData, mContainer.filter, nil, config)
 	if err != nil {
-		mContainer.SetState(MigrateFailure)
+		mContainer.Lock()
+		mContainer.state = MigrateFailure
+		mContainer.retries = mContainer.retries + 1
+		mContainer.err = err.Error()
+		mContainer.Unlock()
 		logger.ERROR("[#cluster] migrator container %s error %s", ShortContainerID(mContainer.ID), err.Error())
 		return
 	}
 
 	mContainer.Lock()
 	mContainer.state = MigrateCompleted
+	mContainer.engineIP = engine.IP
+	mContainer.newContainerID = container.Info.ID
+	mContainer.err = ""
 	logger.INFO("[#cluster] migrator container %s > %s to %s", ShortContainerID(mContainer.ID), ShortContainerID(container.Info.ID), engine.IP)
 	mContainer.Unlock()
 	return
 }
 
 // Migrator is exported
+// records are migrate containers outcome, persisted in migrate storage for resuming after cluster restarted.
+// manual migrator waits approval, it is not started until StartNow.
 type Migrator struct {
 	sync.RWMutex
 	MetaID       string
 	Cluster      *Cluster
 	retryCount   int64
 	migrateDelay time.Duration
+	deadline     time.Time
+	manual       bool
 	containers   []*MigrateContainer
+	records      []*entry.MigrationContainer
+	startCh      chan struct{}
 	handler      MigratorHandler
 }
 
@@ -109,10 +182,12 @@ type Migrator struct {
 func NewMigrator(metaid string, containers Containers, cluster *Cluster, migrateDelay time.Duration, handler MigratorHandler) *Migrator {
 
 	mContainers := []*MigrateContainer{}
+	records := []*entry.MigrationContainer{}
 	for _, container := range containers {
-		mContainer := NewMigrateContainer(container.Info.ID, container.BaseConfig)
+		mContainer := newEngineMigrateContainer(container)
 		if mContainer != nil {
 			mContainers = append(mContainers, mContainer)
+			records = append(records, &entry.MigrationContainer{ID: mContainer.ID, State: mContainer.state.String(), Source: mContainer.sourceIP})
 		}
 	}
 
@@ -121,11 +196,122 @@ func NewMigrator(metaid string, containers Containers, cluster *Cluster, migrate
 		Cluster:      cluster,
 		retryCount:   cluster.createRetry,
 		migrateDelay: migrateDelay,
+		deadline:     time.Now().Add(migrateDelay),
 		containers:   mContainers,
+		records:      records,
+		startCh:      make(chan struct{}, 1),
 		handler:      handler,
 	}
 }
 
+// newEngineMigrateContainer creates a migrate container of engine container, source is container engine.
+func newEngineMigrateContainer(container *Container) *MigrateContainer {
+
+	mContainer := NewMigrateContainer(container.Info.ID, container.BaseConfig)
+	if mContainer != nil && container.Engine != nil {
+		mContainer.sourceIP = container.Engine.IP
+	}
+	return mContainer
+}
+
+// resumeMigrator creates a migrator of a storage migration which is not finished, completed and canceled containers are not migrated again.
+// migrating containers are migrated again, because their migrate outcome is unknown.
+// migrator is delayed at least migrateDelay, so that engines are discovered again after cluster restarted.
+func resumeMigrator(migration *entry.Migration, cluster *Cluster, migrateDelay time.Duration, handler MigratorHandler) *Migrator {
+
+	mContainers := []*MigrateContainer{}
+	for _, record := range migration.Containers {
+		if record.State == MigrateState(MigrateCompleted).String() || record.State == migrateCanceledText {
+			continue
+		}
+		mContainer := NewMigrateContainer(record.ID, cluster.configCache.GetContainerBaseConfig(migration.MetaID, record.ID))
+		if mContainer == nil {
+			record.State = MigrateState(MigrateFailure).String()
+			record.Error = "container base config not found"
+			continue
+		}
+		mContainer.retries = record.Retries
+		mContainer.sourceIP = record.Source
+		record.State = mContainer.state.String()
+		mContainers = append(mContainers, mContainer)
+	}
+
+	delay := migration.Deadline.Sub(time.Now())
+	if delay < migrateDelay {
+		delay = migrateDelay
+	}
+
+	deadline := time.Now().Add(delay)
+	if migration.Manual {
+		deadline = time.Time{}
+	}
+
+	return &Migrator{
+		MetaID:       migration.MetaID,
+		Cluster:      cluster,
+		retryCount:   migration.RetryCount,
+		migrateDelay: delay,
+		deadline:     deadline,
+		manual:       migration.Manual,
+		containers:   mContainers,
+		records:      migration.Containers,
+		startCh:      make(chan struct{}, 1),
+		handler:      handler,
+	}
+}
+
+// record updates outcome record of migrate container, state is migrate container state text.
+func (migrator *Migrator) record(mContainer *MigrateContainer, state string) {
+
+	mContainer.RLock()
+	value := entry.MigrationContainer{
+		ID:             mContainer.ID,
+		State:          state,
+		Source:         mContainer.sourceIP,
+		Retries:        mContainer.retries,
+		NewContainerID: mContainer.newContainerID,
+		Engine:         mContainer.engineIP,
+		Error:          mContainer.err,
+	}
+	mContainer.RUnlock()
+
+	migrator.Lock()
+	defer migrator.Unlock()
+	for _, record := range migrator.records {
+		if record.ID == value.ID {
+			*record = value
+			return
+		}
+	}
+	migrator.records = append(migrator.records, &value)
+}
+
+// save persists migrator to migrate storage, finished migrator is kept for migrationRetention.
+func (migrator *Migrator) save(finished bool) {
+
+	migrator.RLock()
+	migration := &entry.Migration{
+		MetaID:     migrator.MetaID,
+		Manual:     migrator.manual,
+		RetryCount: migrator.retryCount,
+		Deadline:   migrator.deadline,
+		Containers: []*entry.MigrationContainer{},
+	}
+	for _, record := range migrator.records {
+		value := *record
+		migration.Containers = append(migration.Containers, &value)
+	}
+	migrator.RUnlock()
+
+	if finished {
+		migration.Finished = time.Now()
+	}
+
+	if err := migrator.Cluster.storageDriver.MigrateStorage.SetMigration(migration); err != nil {
+		logger.ERROR("[#cluster] migrator %s save error %s", migrator.MetaID, err.Error())
+	}
+}
+
 func (migrator *Migrator) verifyEngines() bool {
 
 	_, engines, err := migrator.Cluster.GetMetaDataEngines(migrator.MetaID)
@@ -182,16 +368,21 @@ func (migrator *Migrator) resetMigrateContainers() {
 	migrator.Unlock()
 }
 
-func (migrator *Migrator) selectMigrateContainer() *MigrateContainer {
+// selectMigrateContainers returns at most count ready migrate containers.
+func (migrator *Migrator) selectMigrateContainers(count int) []*MigrateContainer {
 
 	migrator.RLock()
 	defer migrator.RUnlock()
+	mContainers := []*MigrateContainer{}
 	for _, mContainer := range migrator.containers {
+		if len(mContainers) >= count {
+			break
+		}
 		if mContainer.GetState() == MigrateReady {
-			return mContainer
+			mContainers = append(mContainers, mContainer)
 		}
 	}
-	return nil
+	return mContainers
 }
 
 func (migrator *Migrator) removeMigrateContainer(mContainer *MigrateContainer) {
@@ -241,7 +432,20 @@ func (migrator *Migrator) Container(containerid string) *MigrateContainer {
 // Start is exported
 func (migrator *Migrator) Start() {
 
-	time.Sleep(migrator.migrateDelay)
+	migrator.RLock()
+	manual := migrator.manual
+	migrator.RUnlock()
+	if manual {
+		<-migrator.startCh //waits migration approved or canceled.
+	} else {
+		delayTimer := time.NewTimer(migrator.migrateDelay)
+		select {
+		case <-delayTimer.C:
+		case <-migrator.startCh: //start now or canceled, skip migrate delay.
+			delayTimer.Stop()
+		}
+	}
+
 	for {
 		migrator.RLock()
 		mContainers := migrator.containers
@@ -254,6 +458,15 @@ func (migrator *Migrator) Start() {
 			if !migrator.verifyEngines() {
 				err := fmt.Errorf("cluster no docker-engine available")
 				logger.ERROR("[#cluster] migrator %s containers error %s.", migrator.MetaID, err)
+				for _, mContainer := range migrator.Containers() {
+					if mContainer.GetState() != MigrateCompleted {
+						mContainer.Lock()
+						mContainer.state = MigrateFailure
+						mContainer.err = err.Error()
+						mContainer.Unlock()
+						migrator.record(mContainer, mContainer.GetState().String())
+					}
+				}
 				migrator.clearMigrateContainers()
 				migrator.Cluster.configCache.ClearContainerBaseConfig(migrator.MetaID)
 				migrator.handler.OnMigratorNotifyHandleFunc(migrator, err)
@@ -261,12 +474,9 @@ func (migrator *Migrator) Start() {
 			}
 		}
 
-		mContainer := migrator.selectMigrateContainer()
-		if mContainer != nil {
-			mContainer.Execute(migrator.Cluster)
-			if mContainer.GetState() == MigrateCompleted {
-				migrator.Cluster.configCache.RemoveContainerBaseConfig(migrator.MetaID, mContainer.ID)
-			}
+		mContainers = migrator.selectMigrateContainers(migrator.Cluster.migtatorCache.parallelism)
+		if len(mContainers) > 0 {
+			migrator.execute(mContainers)
 			continue
 		}
 
@@ -278,7 +488,8 @@ func (migrator *Migrator) Start() {
 		if migrator.retryCount <= 0 {
 			migrator.Lock()
 			for _, mContainer := range migrator.containers {
-				if mContainer.GetState() == MigrateFailure {
+				//containers of draining engine are still running, keep them.
+				if mContainer.GetState() == MigrateFailure && migrator.containerEngine(mContainer.ID) == nil {
 					migrator.Cluster.configCache.RemoveContainerBaseConfig(migrator.MetaID, mContainer.ID)
 				}
 			}
@@ -291,46 +502,222 @@ func (migrator *Migrator) Start() {
 			migrator.handler.OnMigratorNotifyHandleFunc(migrator, fmt.Errorf("meta containers part migrated completed."))
 		}
 		migrator.resetMigrateContainers()
+		migrator.save(false)
 		continue
 	}
+	migrator.save(true)
 	migrator.clearMigrateContainers()
 	migrator.handler.OnMigratorQuitHandleFunc(migrator)
 }
 
+// execute migrate containers concurrently in meta operations queue, after prior operations of meta.
+// every container create takes a cluster migrate slot, and waits for a create slot of target engine from cache engines limiter.
+// original container is removed after migrated if it is still running on a draining engine.
+func (migrator *Migrator) execute(mContainers []*MigrateContainer) {
+
+	cache := migrator.Cluster.migtatorCache
+	metaData := mContainers[0].metaData
+	mutex := sync.Mutex{}
+	operation := NewOperation(metaData.GroupID, metaData.Config.Name, migrator.MetaID, MigrateOperation, func(operation *Operation) (interface{}, error) {
+		failures := 0
+		wgroup := sync.WaitGroup{}
+		for _, mContainer := range mContainers {
+			wgroup.Add(1)
+			go func(mContainer *MigrateContainer) {
+				defer wgroup.Done()
+				cache.acquireSlot()
+				mContainer.filter.SetLimiter(cache.limiter)
+				mContainer.Execute(migrator.Cluster)
+				cache.releaseSlot()
+				switch mContainer.GetState() {
+				case MigrateFailure:
+					mutex.Lock()
+					failures = failures + 1
+					mutex.Unlock()
+				case MigrateCompleted:
+					if engine := migrator.containerEngine(mContainer.ID); engine != nil {
+						if err := engine.RemoveContainer(mContainer.ID); err != nil {
+							logger.ERROR("[#cluster] migrator engine %s remove container %s error %s", engine.IP, ShortContainerID(mContainer.ID), err.Error())
+						}
+					}
+					migrator.Cluster.configCache.RemoveContainerBaseConfig(migrator.MetaID, mContainer.ID)
+				}
+			}(mContainer)
+		}
+		wgroup.Wait()
+		if failures > 0 {
+			return nil, fmt.Errorf("migrate %d containers failure", failures)
+		}
+		return nil, nil
+	})
+	migrator.Cluster.operationsQueue.Submit(operation).Wait()
+	for _, mContainer := range mContainers {
+		migrator.record(mContainer, mContainer.GetState().String())
+	}
+	migrator.save(false)
+}
+
+// containerEngine returns the healthy engine which container is still running on, nil if engine is offline.
+func (migrator *Migrator) containerEngine(containerid string) *Engine {
+
+	_, engines, err := migrator.Cluster.GetMetaDataEngines(migrator.MetaID)
+	if err != nil {
+		return nil
+	}
+
+	for _, engine := range engines {
+		if engine.IsHealthy() && engine.HasContainer(containerid) {
+			return engine
+		}
+	}
+	return nil
+}
+
 // Update is exported
 func (migrator *Migrator) Update(metaid string, containers Containers) {
 
 	for _, container := range containers {
 		if mContainer := migrator.Container(container.Info.ID); mContainer == nil {
-			mContainer = NewMigrateContainer(container.Info.ID, container.BaseConfig)
+			mContainer = newEngineMigrateContainer(container)
 			if mContainer != nil {
 				migrator.Lock()
 				migrator.containers = append(migrator.containers, mContainer)
 				migrator.Unlock()
+				migrator.record(mContainer, mContainer.GetState().String())
 			}
 		}
 	}
+	migrator.save(false)
 }
 
 // Cancel is exported
 func (migrator *Migrator) Cancel(metaid string, containers Containers) {
 
+	containerids := []string{}
 	for _, container := range containers {
-		if mContainer := migrator.Container(container.Info.ID); mContainer != nil {
+		containerids = append(containerids, container.Info.ID)
+	}
+	migrator.cancel(containerids)
+}
+
+// CancelAll is exported
+// cancel all containers which are not migrating or migrated, migrator quits without waiting migrate delay.
+// canceled containers are left to recovery.
+func (migrator *Migrator) CancelAll() {
+
+	containerids := []string{}
+	for _, mContainer := range migrator.Containers() {
+		containerids = append(containerids, mContainer.ID)
+	}
+	migrator.cancel(containerids)
+	migrator.StartNow()
+}
+
+// StartNow is exported
+// migrator starts migrating containers without waiting migrate delay.
+func (migrator *Migrator) StartNow() {
+
+	migrator.Lock()
+	migrator.manual = false
+	migrator.deadline = time.Now()
+	migrator.Unlock()
+	migrator.wake()
+}
+
+// wake wakes migrator which is waiting migrate delay or approval.
+func (migrator *Migrator) wake() {
+
+	select {
+	case migrator.startCh <- struct{}{}:
+	default:
+	}
+}
+
+// Pending is exported
+// Determine if migrator is a manual migrator which is waiting approval.
+func (migrator *Migrator) Pending() bool {
+
+	migrator.RLock()
+	defer migrator.RUnlock()
+	return migrator.manual
+}
+
+// cancel removes containers of state is MigrateReady or MigrateFailure from migrator.
+func (migrator *Migrator) cancel(containerids []string) {
+
+	for _, containerid := range containerids {
+		if mContainer := migrator.Container(containerid); mContainer != nil {
 			state := mContainer.GetState()
 			if state == MigrateReady || state == MigrateFailure {
 				migrator.removeMigrateContainer(mContainer)
+				migrator.record(mContainer, migrateCanceledText)
+			}
+		}
+	}
+	migrator.save(false)
+}
+
+// Migration is exported
+// return migrator snapshot, state of containers in migrator is current state, others are recorded outcome.
+func (migrator *Migrator) Migration() *types.Migration {
+
+	mContainers := migrator.Containers()
+	migrator.RLock()
+	migration := &types.Migration{
+		MetaID:     migrator.MetaID,
+		Manual:     migrator.manual,
+		RetryCount: migrator.retryCount,
+		Deadline:   migrator.deadline,
+		Containers: []*types.MigrateContainer{},
+	}
+	for _, record := range migrator.records {
+		migration.Containers = append(migration.Containers, &types.MigrateContainer{
+			ID:             record.ID,
+			State:          record.State,
+			Source:         record.Source,
+			Target:         record.Engine,
+			NewContainerID: record.NewContainerID,
+			Retries:        record.Retries,
+			Error:          record.Error,
+		})
+	}
+	migrator.RUnlock()
+
+	for _, container := range migration.Containers {
+		for _, mContainer := range mContainers {
+			if mContainer.ID == container.ID {
+				mContainer.RLock()
+				container.State = mContainer.state.String()
+				container.Retries = mContainer.retries
+				container.Error = mContainer.err
+				mContainer.RUnlock()
+				break
 			}
 		}
 	}
+
+	if metaData := migrator.Cluster.GetMetaData(migrator.MetaID); metaData != nil {
+		migration.GroupID = metaData.GroupID
+		migration.Name = metaData.Config.Name
+	}
+	return migration
 }
 
 // Clear is exported
+// cancel all containers of migrator, and wake migrator to quit if it is waiting migrate delay or approval.
 func (migrator *Migrator) Clear() {
 
+	mContainers := migrator.Containers()
 	migrator.Lock()
 	migrator.containers = []*MigrateContainer{}
 	migrator.Unlock()
+	for _, mContainer := range mContainers {
+		if mContainer.GetState() != MigrateCompleted {
+			migrator.record(mContainer, migrateCanceledText)
+		}
+	}
+	migrator.save(false)
+	migrator.wake()
 }
 
 // MigratorHandler is exported
@@ -356,23 +743,57 @@ func (fn MigratorNotifyHandleFunc) OnMigratorNotifyHandleFunc(migrator *Migrator
 }
 
 // MigrateContainersCache is exported
+// slots limits concurrent migrate creates of cluster, limiter limits concurrent migrate creates of every target engine.
+// parallelism is the max concurrent migrate containers of a meta.
 type MigrateContainersCache struct {
 	sync.RWMutex
 	MigratorHandler
 	Cluster      *Cluster
 	migrateDelay time.Duration
+	parallelism  int
+	slots        chan struct{}
+	limiter      *EnginesLimiter
 	migrators    map[string]*Migrator
 }
 
 // NewMigrateContainersCache is exported
-func NewMigrateContainersCache(migrateDelay time.Duration) *MigrateContainersCache {
+// concurrency and engineConcurrency is 0, concurrent migrate creates are unlimited.
+func NewMigrateContainersCache(migrateDelay time.Duration, concurrency int, engineConcurrency int, parallelism int) *MigrateContainersCache {
+
+	var slots chan struct{}
+	if concurrency > 0 {
+		slots = make(chan struct{}, concurrency)
+	}
+
+	if parallelism <= 0 {
+		parallelism = 1
+	}
 
 	return &MigrateContainersCache{
 		migrateDelay: migrateDelay,
+		parallelism:  parallelism,
+		slots:        slots,
+		limiter:      NewEnginesLimiter(engineConcurrency),
 		migrators:    make(map[string]*Migrator),
 	}
 }
 
+// acquireSlot waits a cluster migrate slot.
+func (cache *MigrateContainersCache) acquireSlot() {
+
+	if cache.slots != nil {
+		cache.slots <- struct{}{}
+	}
+}
+
+// releaseSlot releases a cluster migrate slot.
+func (cache *MigrateContainersCache) releaseSlot() {
+
+	if cache.slots != nil {
+		<-cache.slots
+	}
+}
+
 // SetCluster is exported
 func (cache *MigrateContainersCache) SetCluster(cluster *Cluster) {
 
@@ -388,19 +809,124 @@ func (cache *MigrateContainersCache) Contains(metaid string) bool {
 	return ret
 }
 
+// Migrating is exported
+// Determine if meta has a migrator which is not waiting approval, pending migrator doesn't lock meta.
+func (cache *MigrateContainersCache) Migrating(metaid string) bool {
+
+	cache.RLock()
+	defer cache.RUnlock()
+	if migrator, ret := cache.migrators[metaid]; ret {
+		return !migrator.Pending()
+	}
+	return false
+}
+
+// ClearPending is exported
+// meta is updated or removed, the migrator of meta which is waiting approval is cleared.
+func (cache *MigrateContainersCache) ClearPending(metaid string) {
+
+	cache.Lock()
+	defer cache.Unlock()
+	if migrator, ret := cache.migrators[metaid]; ret && migrator.Pending() {
+		delete(cache.migrators, metaid)
+		migrator.Clear()
+		logger.INFO("[#cluster] migrator clear pending %s", metaid)
+	}
+}
+
 // RemoveGroup is exported
 // cancel group all metadata migrate.
 func (cache *MigrateContainersCache) RemoveGroup(groupid string) {
 
-	cache.RLock()
+	cache.Lock()
 	groupMetaData := cache.Cluster.configCache.GetGroupMetaData(groupid)
 	for _, metaData := range groupMetaData {
 		if migrator, ret := cache.migrators[metaData.MetaID]; ret {
+			delete(cache.migrators, metaData.MetaID)
 			migrator.Clear()
 			logger.INFO("[#cluster] migrator clear %s", migrator.MetaID)
 		}
 	}
+	cache.Unlock()
+}
+
+// Migrations is exported
+// return snapshots of active migrators.
+func (cache *MigrateContainersCache) Migrations() []*types.Migration {
+
+	cache.RLock()
+	migrators := []*Migrator{}
+	for _, migrator := range cache.migrators {
+		migrators = append(migrators, migrator)
+	}
 	cache.RUnlock()
+
+	migrations := []*types.Migration{}
+	for _, migrator := range migrators {
+		migrations = append(migrations, migrator.Migration())
+	}
+	return migrations
+}
+
+// CancelMigrator is exported
+// cancel migrator of metaid, return false if meta has no active migrator.
+func (cache *MigrateContainersCache) CancelMigrator(metaid string) bool {
+
+	cache.RLock()
+	migrator, ret := cache.migrators[metaid]
+	cache.RUnlock()
+	if ret {
+		logger.INFO("[#cluster] migrator cancel all %s", metaid)
+		migrator.CancelAll()
+	}
+	return ret
+}
+
+// StartMigrator is exported
+// start migrator of metaid without waiting migrate delay, return false if meta has no active migrator.
+func (cache *MigrateContainersCache) StartMigrator(metaid string) bool {
+
+	cache.RLock()
+	migrator, ret := cache.migrators[metaid]
+	cache.RUnlock()
+	if ret {
+		logger.INFO("[#cluster] migrator start now %s", metaid)
+		migrator.StartNow()
+	}
+	return ret
+}
+
+// Resume is exported
+// resume migrations which are not finished before cluster restarted, finished migrations out of retention are deleted.
+func (cache *MigrateContainersCache) Resume() {
+
+	migrateStorage := cache.Cluster.storageDriver.MigrateStorage
+	migrations, err := migrateStorage.Migrations()
+	if err != nil {
+		logger.ERROR("[#cluster] migrator resume error %s", err.Error())
+		return
+	}
+
+	cache.Lock()
+	for _, migration := range migrations {
+		if !migration.Finished.IsZero() {
+			if time.Since(migration.Finished) > migrationRetention {
+				migrateStorage.DeleteMigration(migration.MetaID)
+			}
+			continue
+		}
+
+		migrator := resumeMigrator(migration, cache.Cluster, cache.migrateDelay, cache)
+		if len(migrator.Containers()) == 0 {
+			migrator.save(true)
+			continue
+		}
+		migrator.save(false)
+		cache.migrators[migration.MetaID] = migrator
+		logger.INFO("[#cluster] migrator resume %s, %d containers", migration.MetaID, len(migrator.Containers()))
+		go migrator.Start()
+	}
+	cache.Unlock()
 }
 
 // Start is exported
@@ -408,9 +934,33 @@ func (cache *MigrateContainersCache) RemoveGroup(groupid string) {
 // engine parameter is offline engine pointer.
 func (cache *MigrateContainersCache) Start(engine *Engine) {
 
+	if engine.IsHealthy() || engine.IsUnhealthy() {
+		metaids := engine.MetaIds()
+		cache.start(engine, metaids, true)
+	}
+}
+
+// Drain is exported
+// engine availability is drain, migrate containers to active engines without delay,
+// original containers are removed after migrated.
+func (cache *MigrateContainersCache) Drain(engine *Engine) {
+
 	if engine.IsHealthy() {
 		metaids := engine.MetaIds()
-		cache.start(engine, metaids)
+		logger.INFO("[#cluster] migrator drain engine %s", engine.IP)
+		cache.start(engine, metaids, false)
+	}
+}
+
+// Evacuate is exported
+// engine is unhealthy over probe grace period, migrate containers to active engines without delay,
+// original containers are expelled when engine is healthy again.
+func (cache *MigrateContainersCache) Evacuate(engine *Engine) {
+
+	if engine.IsUnhealthy() {
+		metaids := engine.MetaIds()
+		logger.INFO("[#cluster] migrator evacuate engine %s", engine.IP)
+		cache.start(engine, metaids, false)
 	}
 }
 
@@ -425,20 +975,37 @@ func (cache *MigrateContainersCache) Cancel(engine *Engine) {
 	}
 }
 
-func (cache *MigrateContainersCache) start(engine *Engine, metaids []string) {
+// start migrate containers of metaids on engine by meta migrate policy, never policy metas are not migrated,
+// manual policy migrators wait approval. delayed migrators start after meta migrate delay, others start without delay.
+func (cache *MigrateContainersCache) start(engine *Engine, metaids []string, delayed bool) {
 
 	if len(metaids) > 0 {
+		pendings := []string{}
 		cache.Lock()
 		for _, metaid := range metaids {
 			containers := engine.Containers(metaid)
 			if len(containers) == 0 {
 				continue
 			}
+			mode, migrateDelay := migratePolicy(cache.Cluster.GetMetaData(metaid), cache.migrateDelay)
+			if mode == NeverMigratePolicy {
+				logger.INFO("[#cluster] migrator skip %s %s, migrate policy is %s", engine.IP, metaid, mode)
+				continue
+			}
+			if !delayed {
+				migrateDelay = 0
+			}
 			migrator, ret := cache.migrators[metaid]
 			if !ret {
-				migrator = NewMigrator(metaid, containers, cache.Cluster, cache.migrateDelay, cache)
+				migrator = NewMigrator(metaid, containers, cache.Cluster, migrateDelay, cache)
+				if mode == ManualMigratePolicy {
+					migrator.manual = true
+					migrator.deadline = time.Time{}
+					pendings = append(pendings, metaid)
+				}
+				migrator.save(false)
 				cache.migrators[metaid] = migrator
-				logger.INFO("[#cluster] migrator start %s %s", engine.IP, metaid)
+				logger.INFO("[#cluster] migrator start %s %s, migrate policy is %s", engine.IP, metaid, mode)
 				go migrator.Start()
 			} else {
 				logger.INFO("[#cluster] migrator update %s %s", engine.IP, metaid)
@@ -446,6 +1013,9 @@ func (cache *MigrateContainersCache) start(engine *Engine, metaids []string) {
 			}
 		}
 		cache.Unlock()
+		for _, metaid := range pendings {
+			cache.Cluster.NotifyGroupMetaContainersEvent("Cluster Meta Containers Migration Pending Approval.", nil, metaid)
+		}
 	}
 }
 
@@ -478,7 +1048,9 @@ func (cache *MigrateContainersCache) OnMigratorQuitHandleFunc(migrator *Migrator
 
 	logger.INFO("[#cluster] migrator quited %s", migrator.MetaID)
 	cache.Lock()
-	delete(cache.migrators, migrator.MetaID)
+	if current, ret := cache.migrators[migrator.MetaID]; ret && current == migrator {
+		delete(cache.migrators, migrator.MetaID)
+	}
 	cache.Unlock()
 }
 
@@ -494,3 +1066,30 @@ func (cache *MigrateContainersCache) OnMigratorNotifyHandleFunc(migrator *Migrat
 		logger.INFO("[#cluster] migrator container %s %s", ShortContainerID(mContainer.ID), mContainer.state.String())
 	}
 }
+
+// GetMigrations is exported
+// return active migrations of cluster.
+func (cluster *Cluster) GetMigrations() []*types.Migration {
+
+	return cluster.migtatorCache.Migrations()
+}
+
+// CancelMigration is exported
+// cancel containers of meta migration which are not migrating or migrated.
+func (cluster *Cluster) CancelMigration(metaid string) error {
+
+	if ret := cluster.migtatorCache.CancelMigrator(metaid); !ret {
+		return ErrClusterMigrationNotFound
+	}
+	return nil
+}
+
+// StartMigration is exported
+// start meta migration immediately, skip migrate delay.
+func (cluster *Cluster) StartMigration(metaid string) error {
+
+	if ret := cluster.migtatorCache.StartMigrator(metaid); !ret {
+		return ErrClusterMigrationNotFound
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package cluster
+
+import "github.com/humpback/humpback-center/cluster/types"
+
+import (
+	"testing"
+	"time"
+)
+
+func TestValidateMigratePolicy(t *testing.T) {
+
+	tests := []struct {
+		policy types.MigratePolicy
+		valid  bool
+	}{
+		{types.MigratePolicy{}, true},
+		{types.MigratePolicy{Mode: "auto"}, true},
+		{types.MigratePolicy{Mode: " Manual "}, true},
+		{types.MigratePolicy{Mode: "never", Delay: "10m"}, true},
+		{types.MigratePolicy{Delay: "0s"}, true},
+		{types.MigratePolicy{Mode: "always"}, false},
+		{types.MigratePolicy{Delay: "10"}, false},
+		{types.MigratePolicy{Delay: "-1m"}, false},
+	}
+
+	for _, test := range tests {
+		err := ValidateMigratePolicy(test.policy)
+		if test.valid && err != nil {
+			t.Errorf("ValidateMigratePolicy(%+v) error %s, expected valid", test.policy, err)
+		}
+		if !test.valid && err != ErrClusterMigratePolicyInvalid {
+			t.Errorf("ValidateMigratePolicy(%+v) error is %v, expected %s", test.policy, err, ErrClusterMigratePolicyInvalid)
+		}
+	}
+}
+
+func TestMigratePolicy(t *testing.T) {
+
+	migrateDelay := time.Minute * 5
+	tests := []struct {
+		policy *types.MigratePolicy
+		mode   string
+		delay  time.Duration
+	}{
+		{nil, AutoMigratePolicy, migrateDelay},
+		{&types.MigratePolicy{}, AutoMigratePolicy, migrateDelay},
+		{&types.MigratePolicy{Mode: "MANUAL"}, ManualMigratePolicy, migrateDelay},
+		{&types.MigratePolicy{Mode: "never", Delay: "30s"}, NeverMigratePolicy, time.Second * 30},
+		{&types.MigratePolicy{Delay: "0s"}, AutoMigratePolicy, 0},
+		{&types.MigratePolicy{Delay: "-1m"}, AutoMigratePolicy, migrateDelay},
+	}
+
+	for _, test := range tests {
+		var metaData *MetaData
+		if test.policy != nil {
+			metaData = &MetaData{MetaBase: MetaBase{MigratePolicy: *test.policy}}
+		}
+		mode, delay := migratePolicy(metaData, migrateDelay)
+		if mode != test.mode || delay != test.delay {
+			t.Errorf("migratePolicy(%+v) is %s %s, expected %s %s", test.policy, mode, delay, test.mode, test.delay)
+		}
+	}
+}
@@ -0,0 +1,344 @@
+package cluster
+
+import "github.com/humpback/humpback-center/cluster/types"
+import "github.com/humpback/gounits/logger"
+import "github.com/humpback/gounits/rand"
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// OperationKind is exported
+type OperationKind string
+
+const (
+	CreateOperation    OperationKind = "create"
+	UpdateOperation    OperationKind = "update"
+	UpgradeOperation   OperationKind = "upgrade"
+	RemoveOperation    OperationKind = "remove"
+	OperateOperation   OperationKind = "operate"
+	RecoveryOperation  OperationKind = "recovery"
+	MigrateOperation   OperationKind = "migrate"
+	RebalanceOperation OperationKind = "rebalance"
+	EvictOperation     OperationKind = "evict"
+)
+
+// OperationState is exported
+type OperationState string
+
+const (
+	OperationQueued    OperationState = "queued"
+	OperationRunning   OperationState = "running"
+	OperationCompleted OperationState = "completed"
+	OperationFailed    OperationState = "failed"
+)
+
+// operationRetention is how long finished operations are kept for query.
+var operationRetention = time.Duration(time.Minute * 30)
+
+// OperationHandleFunc is exported
+// operation job function, result is kept on operation.
+type OperationHandleFunc func(operation *Operation) (interface{}, error)
+
+// Operation is exported
+// a job of meta, MetaID identifies the meta queue,
+// MetaID of a new meta create operation is empty until meta is made, GroupID and Name identify its queue.
+type Operation struct {
+	sync.RWMutex
+	ID       string
+	GroupID  string
+	Name     string
+	Kind     OperationKind
+	metaID   string
+	state    OperationState
+	result   interface{}
+	err      error
+	created  time.Time
+	started  time.Time
+	finished time.Time
+	handler  OperationHandleFunc
+	doneCh   chan struct{}
+}
+
+// NewOperation is exported
+func NewOperation(groupid string, name string, metaid string, kind OperationKind, handler OperationHandleFunc) *Operation {
+
+	return &Operation{
+		ID:      rand.UUID(true),
+		GroupID: groupid,
+		Name:    name,
+		Kind:    kind,
+		metaID:  metaid,
+		state:   OperationQueued,
+		created: time.Now(),
+		handler: handler,
+		doneCh:  make(chan struct{}),
+	}
+}
+
+// MetaID is exported
+func (operation *Operation) MetaID() string {
+
+	operation.RLock()
+	defer operation.RUnlock()
+	return operation.metaID
+}
+
+// SetMetaID is exported
+func (operation *Operation) SetMetaID(metaid string) {
+
+	operation.Lock()
+	operation.metaID = metaid
+	operation.Unlock()
+}
+
+// State is exported
+func (operation *Operation) State() OperationState {
+
+	operation.RLock()
+	defer operation.RUnlock()
+	return operation.state
+}
+
+// Wait is exported
+// block until operation is finished, return job result and error.
+func (operation *Operation) Wait() (interface{}, error) {
+
+	<-operation.doneCh
+	operation.RLock()
+	defer operation.RUnlock()
+	return operation.result, operation.err
+}
+
+// Operation is exported
+// return operation snapshot.
+func (operation *Operation) Operation() *types.Operation {
+
+	operation.RLock()
+	defer operation.RUnlock()
+	snapshot := &types.Operation{
+		ID:       operation.ID,
+		GroupID:  operation.GroupID,
+		MetaID:   operation.metaID,
+		Name:     operation.Name,
+		Kind:     string(operation.Kind),
+		State:    string(operation.state),
+		Result:   operation.result,
+		Created:  operation.created,
+		Started:  operation.started,
+		Finished: operation.finished,
+	}
+	if operation.err != nil {
+		snapshot.Error = operation.err.Error()
+	}
+	return snapshot
+}
+
+func (operation *Operation) execute() {
+
+	operation.Lock()
+	operation.state = OperationRunning
+	operation.started = time.Now()
+	operation.Unlock()
+
+	result, err := operation.handle()
+	operation.Lock()
+	operation.result = result
+	operation.err = err
+	operation.state = OperationCompleted
+	if err != nil {
+		operation.state = OperationFailed
+	}
+	operation.finished = time.Now()
+	operation.Unlock()
+	close(operation.doneCh)
+}
+
+// handle calls operation job function, a panic of job is recovered as operation error,
+// so the meta queue goes on with next operations.
+func (operation *Operation) handle() (result interface{}, err error) {
+
+	defer func() {
+		if r := recover(); r != nil {
+			logger.ERROR("[#cluster#] operation %s %s panic, %v", operation.ID, operation.Kind, r)
+			result = nil
+			err = fmt.Errorf("operation %s panic, %v", operation.Kind, r)
+		}
+	}()
+	return operation.handler(operation)
+}
+
+func (operation *Operation) isExpired() bool {
+
+	operation.RLock()
+	defer operation.RUnlock()
+	return !operation.finished.IsZero() && time.Since(operation.finished) > operationRetention
+}
+
+// OperationsQueue is exported
+// serialize operations of every meta, the first operation of a queue is running.
+// queues are keyed by metaid, so operations of a meta are in one queue even if meta is renamed.
+// create operation of a new meta is keyed by meta groupid and name, operations of the meta join that queue until it is finished.
+type OperationsQueue struct {
+	sync.RWMutex
+	queues     map[string][]*Operation
+	operations map[string]*Operation
+}
+
+// NewOperationsQueue is exported
+func NewOperationsQueue() *OperationsQueue {
+
+	return &OperationsQueue{
+		queues:     make(map[string][]*Operation),
+		operations: make(map[string]*Operation),
+	}
+}
+
+func operationNameKey(groupid string, name string) string {
+
+	return groupid + "/" + name
+}
+
+// queueKey returns the queue key of operation, queue lock is held by caller.
+func (queue *OperationsQueue) queueKey(operation *Operation) string {
+
+	nameKey := operationNameKey(operation.GroupID, operation.Name)
+	metaid := operation.MetaID()
+	if metaid == "" {
+		return nameKey
+	}
+
+	//meta may be made by the create operation of name queue, wait for it.
+	if len(queue.queues[metaid]) == 0 && len(queue.queues[nameKey]) > 0 {
+		return nameKey
+	}
+	return metaid
+}
+
+// Submit is exported
+// append operation to its meta queue, operation is executed after all prior operations of the meta.
+func (queue *OperationsQueue) Submit(operation *Operation) *Operation {
+
+	queue.submit(operation, false)
+	return operation
+}
+
+// TrySubmit is exported
+// submit operation only if its meta has no queued or running operations, return false if meta is busy.
+func (queue *OperationsQueue) TrySubmit(operation *Operation) bool {
+
+	return queue.submit(operation, true)
+}
+
+func (queue *OperationsQueue) submit(operation *Operation, idle bool) bool {
+
+	queue.Lock()
+	if idle && queue.busy(operation.GroupID, operation.Name, operation.MetaID()) {
+		queue.Unlock()
+		return false
+	}
+
+	key := queue.queueKey(operation)
+	pending := queue.queues[key]
+
+	for id, op := range queue.operations {
+		if op.isExpired() {
+			delete(queue.operations, id)
+		}
+	}
+	queue.operations[operation.ID] = operation
+	queue.queues[key] = append(pending, operation)
+	queue.Unlock()
+
+	logger.INFO("[#cluster#] operation %s %s %s queued, %d pending.", operation.ID, operation.Kind, key, len(pending))
+	if len(pending) == 0 {
+		go queue.run(key)
+	}
+	return true
+}
+
+// Get is exported
+func (queue *OperationsQueue) Get(id string) *Operation {
+
+	queue.RLock()
+	defer queue.RUnlock()
+	return queue.operations[id]
+}
+
+// Busy is exported
+// return true if meta has queued or running operations, a new meta is identified by groupid and name.
+func (queue *OperationsQueue) Busy(groupid string, name string, metaid string) bool {
+
+	queue.RLock()
+	defer queue.RUnlock()
+	return queue.busy(groupid, name, metaid)
+}
+
+// busy is Busy, queue lock is held by caller.
+func (queue *OperationsQueue) busy(groupid string, name string, metaid string) bool {
+
+	if len(queue.queues[operationNameKey(groupid, name)]) > 0 {
+		return true
+	}
+
+	if metaid == "" {
+		return false
+	}
+
+	//operations of meta may wait in the name queue of the create operation which makes meta.
+	for _, pending := range queue.queues {
+		for _, operation := range pending {
+			if operation.MetaID() == metaid {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (queue *OperationsQueue) run(key string) {
+
+	for {
+		queue.RLock()
+		operation := queue.queues[key][0]
+		queue.RUnlock()
+
+		operation.execute()
+		if err := operation.err; err != nil {
+			logger.ERROR("[#cluster#] operation %s %s %s failed, %s", operation.ID, operation.Kind, key, err.Error())
+		} else {
+			logger.INFO("[#cluster#] operation %s %s %s completed.", operation.ID, operation.Kind, key)
+		}
+
+		queue.Lock()
+		pending := queue.queues[key][1:]
+		if len(pending) == 0 {
+			delete(queue.queues, key)
+			queue.Unlock()
+			return
+		}
+		queue.queues[key] = pending
+		queue.Unlock()
+	}
+}
+
+// GetOperation is exported
+// return operation snapshot of id, finished operations are kept for a while.
+func (cluster *Cluster) GetOperation(id string) (*types.Operation, error) {
+
+	operation := cluster.operationsQueue.Get(id)
+	if operation == nil {
+		return nil, ErrClusterOperationNotFound
+	}
+	return operation.Operation(), nil
+}
+
+// submitOperation is exported
+// queue a job of meta, job is executed after all prior jobs of meta.
+func (cluster *Cluster) submitOperation(metaData *MetaData, kind OperationKind, handler OperationHandleFunc) *Operation {
+
+	operation := NewOperation(metaData.GroupID, metaData.Config.Name, metaData.MetaID, kind, handler)
+	return cluster.operationsQueue.Submit(operation)
+}
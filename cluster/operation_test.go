@@ -0,0 +1,71 @@
+package cluster
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestOperationsQueue(t *testing.T) {
+
+	queue := NewOperationsQueue()
+	var (
+		lock  sync.Mutex
+		order []string
+	)
+
+	block := make(chan struct{})
+	handler := func(name string) OperationHandleFunc {
+		return func(operation *Operation) (interface{}, error) {
+			if name == "create" {
+				<-block
+				operation.SetMetaID("meta-1")
+			}
+			lock.Lock()
+			order = append(order, name)
+			lock.Unlock()
+			return name, nil
+		}
+	}
+
+	//operations of meta-1 wait for the create operation which makes meta-1, a renamed meta keeps its queue.
+	create := queue.Submit(NewOperation("group-1", "web", "", CreateOperation, handler("create")))
+	update := queue.Submit(NewOperation("group-1", "web", "meta-1", UpdateOperation, handler("update")))
+	if queue.TrySubmit(NewOperation("group-1", "web", "meta-1", EvictOperation, handler("evict"))) {
+		t.Errorf("TrySubmit of busy meta is submitted")
+	}
+
+	if !queue.Busy("group-1", "web", "") || !queue.Busy("group-1", "web-renamed", "meta-1") {
+		t.Errorf("Busy of queued meta is false")
+	}
+
+	close(block)
+	if _, err := update.Wait(); err != nil {
+		t.Errorf("update operation error %s", err)
+	}
+
+	renamed := queue.Submit(NewOperation("group-1", "web-renamed", "meta-1", UpdateOperation, handler("renamed")))
+	remove := queue.Submit(NewOperation("group-1", "web", "meta-1", RemoveOperation, handler("remove")))
+	if _, err := remove.Wait(); err != nil {
+		t.Errorf("remove operation error %s", err)
+	}
+	renamed.Wait()
+
+	expected := []string{"create", "update", "renamed", "remove"}
+	if !equalStrings(order, expected) {
+		t.Errorf("operations order is %v, expected %v", order, expected)
+	}
+
+	if create.MetaID() != "meta-1" || queue.Get(create.ID) == nil {
+		t.Errorf("create operation metaid is %q, expected meta-1", create.MetaID())
+	}
+
+	if queue.Busy("group-1", "web", "meta-1") {
+		t.Errorf("Busy of idle meta is true")
+	}
+
+	evict := NewOperation("group-1", "web", "meta-1", EvictOperation, handler("evict"))
+	if !queue.TrySubmit(evict) {
+		t.Errorf("TrySubmit of idle meta isn't submitted")
+	}
+	evict.Wait()
+}
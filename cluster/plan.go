@@ -0,0 +1,193 @@
+package cluster
+
+import "github.com/humpback/common/models"
+import "github.com/humpback/gounits/rand"
+import "github.com/humpback/humpback-center/cluster/types"
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// PlanContainers is exported
+// dry-run a create or update request, return which engine each instance would land on
+// and why every other engine was rejected, nothing is created.
+// instances are scheduled by selectCreateEngine on snapshots of group engines, planned instances are reserved on snapshots.
+// metaid is empty string so plan create containers to groupid, otherwise plan update metaid containers.
+func (cluster *Cluster) PlanContainers(groupid string, metaid string, instances int, placement types.Placement, config models.Container) (*types.PlanContainers, error) {
+
+	if instances < 0 || (metaid == "" && instances == 0) {
+		return nil, ErrClusterContainersInstancesInvalid
+	}
+
+	if _, err := NewStrategy(placement.Strategy); err != nil {
+		return nil, err
+	}
+
+	if err := validatePortRange(placement); err != nil {
+		return nil, err
+	}
+
+	if err := validateAffinities(placement); err != nil {
+		return nil, err
+	}
+
+	if err := ValidateReducePolicy(placement.ReducePolicy); err != nil {
+		return nil, err
+	}
+
+	var (
+		metaData    *MetaData
+		engines     []*Engine
+		action      string
+		count       int
+		reCreated   bool
+		prioritized bool
+	)
+
+	if metaid == "" {
+		engines = cluster.GetGroupEngines(groupid)
+		if engines == nil {
+			return nil, ErrClusterGroupNotFound
+		}
+		metaData = &MetaData{MetaBase: MetaBase{GroupID: groupid}}
+		action = "create"
+		count = instances
+	} else {
+		var err error
+		metaData, engines, err = cluster.GetMetaDataEngines(metaid)
+		if err != nil {
+			return nil, err
+		}
+		if config.Name == "" {
+			config = metaData.Config
+		}
+		originalInstances := cluster.configCache.GetMetaDataBaseConfigsCount(metaid)
+		placementCompared := comparePlacement(metaData.Placement, placement)
+		if instances == 0 {
+			action = "reduce"
+		} else if !reflect.DeepEqual(metaData.Config, config) || !placementCompared || metaData.AvailableNodesChanged {
+			action = "re-create"
+			count = instances
+			reCreated = true
+			prioritized = originalInstances == instances && placementCompared && !metaData.AvailableNodesChanged
+		} else if originalInstances < instances {
+			action = "append"
+			count = instances - originalInstances
+		} else if originalInstances > instances {
+			action = "reduce"
+		} else {
+			action = "none"
+		}
+	}
+
+	planContainers := &types.PlanContainers{
+		GroupID:   metaData.GroupID,
+		MetaID:    metaData.MetaID,
+		Action:    action,
+		Instances: instances,
+		Plans:     []*types.PlanInstance{},
+	}
+
+	if instances > 0 {
+		if err := cluster.checkGroupQuota(metaData.GroupID, metaData.MetaID, config.Name, instances, config); err != nil {
+			if err != ErrClusterGroupQuotaExceeded {
+				return nil, err
+			}
+			planContainers.Error = err.Error()
+		}
+	}
+
+	snapshots := []*Engine{}
+	for _, engine := range engines {
+		snapshots = append(snapshots, engine.snapshot())
+	}
+
+	//update engine priorities are the engines of original meta containers, before they are removed.
+	var priorities *EnginePriorities
+	if prioritized {
+		priorities = NewEnginePriorities(metaData, snapshots)
+	}
+
+	planMeta := &MetaData{MetaBase: metaData.MetaBase}
+	planMeta.Instances = instances
+	planMeta.Placement = placement
+	planMeta.Config = config
+	if planMeta.MetaID == "" { //planned instances of a new meta are counted by a temporary metaid.
+		planMeta.MetaID = rand.UUID(true)
+	}
+
+	if reCreated { //meta containers are removed before re-create.
+		for _, engine := range snapshots {
+			for _, container := range engine.Containers(planMeta.MetaID) {
+				engine.removeSnapshotContainer(container.Info.ID)
+			}
+		}
+	}
+
+	strategy := cluster.selectStrategy(planMeta)
+	planContainers.Strategy = strategy.Name()
+	if planContainers.Error != "" {
+		return planContainers, nil
+	}
+
+	filter := NewEnginesFilter()
+	for index := 1; index <= count; index++ {
+		planContainers.Plans = append(planContainers.Plans, cluster.planContainer(planMeta, filter, priorities, snapshots, index))
+	}
+	return planContainers, nil
+}
+
+// planContainer returns the plan of an instance, engine is selected same as createContainer and reserved on snapshot.
+func (cluster *Cluster) planContainer(metaData *MetaData, filter *EnginesFilter, priorities *EnginePriorities, engines []*Engine, index int) *types.PlanInstance {
+
+	instance := &types.PlanInstance{
+		Index:    index,
+		Evicted:  []string{},
+		Rejected: []types.PlanRejected{},
+	}
+
+	for _, engine := range engines {
+		if engine.IsActive() && engine.HasMeta(metaData.MetaID) {
+			filter.SetAllocEngine(engine)
+		}
+	}
+
+	config := metaData.Config
+	config.Name = fmt.Sprintf("%s-plan-%d", config.Name, index)
+	config = resetDynamicPorts(metaData, config)
+	engine, victims, err := cluster.selectCreateEngine(metaData, filter, priorities, engines, config)
+	rejects := filter.Rejects()
+	for _, e := range engines {
+		if reason, ret := rejects[e.IP]; ret && e != engine {
+			instance.Rejected = append(instance.Rejected, types.PlanRejected{IP: e.IP, HostName: e.Name, Reason: reason})
+		}
+	}
+
+	if err != nil {
+		instance.Error = err.Error()
+		return instance
+	}
+
+	instance.IP = engine.IP
+	instance.HostName = engine.Name
+	if victims != nil {
+		for _, container := range victims.containers {
+			engine.removeSnapshotContainer(container.Info.ID)
+			instance.Evicted = append(instance.Evicted, ShortContainerID(container.Info.ID))
+		}
+	}
+	engine.reservePending(metaData.MetaID, config)
+	filter.SetAllocEngine(engine)
+	return instance
+}
+
+func containsEngine(engines []*Engine, engine *Engine) bool {
+
+	for _, e := range engines {
+		if e == engine {
+			return true
+		}
+	}
+	return false
+}
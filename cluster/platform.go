@@ -0,0 +1,89 @@
+package cluster
+
+import "github.com/humpback/humpback-center/cluster/types"
+
+import (
+	"strings"
+)
+
+// normalize architecture, docker engine reports kernel names (x86_64, aarch64...)
+// but images and users mostly use go names (amd64, arm64...).
+var architectureAliases = map[string]string{
+	"x86_64":  "amd64",
+	"x86-64":  "amd64",
+	"amd64":   "amd64",
+	"aarch64": "arm64",
+	"arm64":   "arm64",
+	"armv8":   "arm64",
+	"armv8l":  "arm64",
+	"armhf":   "arm",
+	"armel":   "arm",
+	"armv6l":  "arm",
+	"armv7l":  "arm",
+	"arm":     "arm",
+	"i386":    "386",
+	"i686":    "386",
+	"x86":     "386",
+	"386":     "386",
+	"ppc64le": "ppc64le",
+	"s390x":   "s390x",
+}
+
+// NormalizeArchitecture is exported
+// return a normalized architecture name.
+func NormalizeArchitecture(architecture string) string {
+
+	architecture = strings.ToLower(strings.TrimSpace(architecture))
+	if value, ret := architectureAliases[architecture]; ret {
+		return value
+	}
+	return architecture
+}
+
+// NormalizeOS is exported
+// return a normalized os name.
+func NormalizeOS(os string) string {
+
+	return strings.ToLower(strings.TrimSpace(os))
+}
+
+// MatchPlatforms returns true if the engine satisfies one of the given platforms.
+// empty platforms match all engines, empty platform field is a wildcard.
+func MatchPlatforms(platforms []types.Platform, engine *Engine) bool {
+
+	if len(platforms) == 0 {
+		return true
+	}
+
+	engine.RLock()
+	engineArchitecture := NormalizeArchitecture(engine.Architecture)
+	engineOS := NormalizeOS(engine.OSType)
+	engine.RUnlock()
+	for _, platform := range platforms {
+		architecture := NormalizeArchitecture(platform.Architecture)
+		os := NormalizeOS(platform.OS)
+		if (architecture == "" || architecture == engineArchitecture) && (os == "" || os == engineOS) {
+			return true
+		}
+	}
+	return false
+}
+
+// PlatformsString is exported
+// return platforms readable description, like 'linux/amd64, linux/arm64'
+func PlatformsString(platforms []types.Platform) string {
+
+	values := []string{}
+	for _, platform := range platforms {
+		os := NormalizeOS(platform.OS)
+		if os == "" {
+			os = "*"
+		}
+		architecture := NormalizeArchitecture(platform.Architecture)
+		if architecture == "" {
+			architecture = "*"
+		}
+		values = append(values, os+"/"+architecture)
+	}
+	return strings.Join(values, ", ")
+}
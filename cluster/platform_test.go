@@ -0,0 +1,62 @@
+package cluster
+
+import "github.com/humpback/humpback-center/cluster/types"
+
+import (
+	"testing"
+)
+
+func TestNormalizeArchitecture(t *testing.T) {
+
+	tests := []struct {
+		architecture string
+		normalized   string
+	}{
+		{"x86_64", "amd64"},
+		{"X86_64", "amd64"},
+		{" amd64 ", "amd64"},
+		{"aarch64", "arm64"},
+		{"armv8l", "arm64"},
+		{"armv7l", "arm"},
+		{"i686", "386"},
+		{"ppc64le", "ppc64le"},
+		{"mips64", "mips64"},
+		{"", ""},
+	}
+
+	for _, test := range tests {
+		if normalized := NormalizeArchitecture(test.architecture); normalized != test.normalized {
+			t.Errorf("NormalizeArchitecture(%q) is %q, expected %q", test.architecture, normalized, test.normalized)
+		}
+	}
+}
+
+func TestMatchPlatforms(t *testing.T) {
+
+	engine := &Engine{
+		OSType:       "linux",
+		Architecture: "x86_64",
+	}
+
+	tests := []struct {
+		platforms []types.Platform
+		match     bool
+	}{
+		{[]types.Platform{}, true},
+		{[]types.Platform{{OS: "linux", Architecture: "amd64"}}, true},
+		{[]types.Platform{{OS: "Linux", Architecture: "x86_64"}}, true},
+		{[]types.Platform{{OS: "linux"}}, true},
+		{[]types.Platform{{Architecture: "amd64"}}, true},
+		{[]types.Platform{{}}, true},
+		{[]types.Platform{{OS: "windows", Architecture: "amd64"}}, false},
+		{[]types.Platform{{OS: "linux", Architecture: "arm64"}}, false},
+		{[]types.Platform{{OS: "linux", Architecture: "arm64"}, {OS: "linux", Architecture: "amd64"}}, true},
+		{[]types.Platform{{OS: "windows"}, {Architecture: "arm64"}}, false},
+	}
+
+	for _, test := range tests {
+		if match := MatchPlatforms(test.platforms, engine); match != test.match {
+			t.Errorf("MatchPlatforms(%s) is %t, expected %t", PlatformsString(test.platforms), match, test.match)
+		}
+	}
+}
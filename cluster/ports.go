@@ -0,0 +1,292 @@
+package cluster
+
+import "github.com/humpback/common/models"
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultPortRange is exported
+// cluster default dynamic host ports range.
+const DefaultPortRange = "30000-32767"
+
+// HostPorts is exported
+// host ports set, key is in the form of 'tcp/8080'.
+type HostPorts map[string]bool
+
+// Conflicts returns the ports of hostPorts already in the set.
+func (ports HostPorts) Conflicts(hostPorts HostPorts) []string {
+
+	conflicts := []string{}
+	for port := range hostPorts {
+		if ports[port] {
+			conflicts = append(conflicts, port)
+		}
+	}
+	sort.Strings(conflicts)
+	return conflicts
+}
+
+// Add is exported
+func (ports HostPorts) Add(hostPorts HostPorts) {
+
+	for port := range hostPorts {
+		ports[port] = true
+	}
+}
+
+func hostPortKey(portType string, port int) string {
+
+	portType = strings.ToLower(strings.TrimSpace(portType))
+	if portType == "" {
+		portType = "tcp"
+	}
+	return portType + "/" + strconv.Itoa(port)
+}
+
+// isBridgeNetwork returns true if container network is bridge or nat.
+func isBridgeNetwork(config models.Container) bool {
+
+	return config.NetworkMode == "bridge" || config.NetworkMode == "nat"
+}
+
+// isBindHostPorts is exported
+// returns true if container config binds host ports, container must be stopped before an other container uses them.
+// bridge or nat network binds published ports, other networks like host may bind any port.
+func isBindHostPorts(config models.Container) bool {
+
+	if !isBridgeNetwork(config) {
+		return true
+	}
+
+	for _, portBindings := range config.Ports {
+		if portBindings.PublicPort != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// configHostPorts returns host ports a container config binds.
+// bridge or nat network binds published ports, host network binds container ports directly.
+func configHostPorts(config models.Container) HostPorts {
+
+	hostPorts := HostPorts{}
+	for _, portBindings := range config.Ports {
+		if portBindings.PublicPort != 0 {
+			hostPorts[hostPortKey(portBindings.Type, portBindings.PublicPort)] = true
+		} else if config.NetworkMode == "host" && portBindings.PrivatePort != 0 {
+			hostPorts[hostPortKey(portBindings.Type, portBindings.PrivatePort)] = true
+		}
+	}
+	return hostPorts
+}
+
+// containersHostPorts returns host ports bound by running containers.
+// stopped containers release host ports, containers of excludeMetaID are skipped.
+func containersHostPorts(containers Containers, excludeMetaID string) HostPorts {
+
+	hostPorts := HostPorts{}
+	for _, container := range containers {
+		if container.Config == nil || container.Info.ContainerJSONBase == nil || container.Info.State == nil {
+			continue
+		}
+		if !container.Info.State.Running && !container.Info.State.Restarting {
+			continue
+		}
+		if excludeMetaID != "" && container.MetaID() == excludeMetaID {
+			continue
+		}
+		hostPorts.Add(configHostPorts(container.Config.Container))
+	}
+	return hostPorts
+}
+
+// PortRange is exported
+// dynamic host ports range, like '30000-32767'.
+type PortRange struct {
+	Start int
+	End   int
+}
+
+// ParsePortRange is exported
+func ParsePortRange(value string) (*PortRange, error) {
+
+	parts := strings.SplitN(strings.TrimSpace(value), "-", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("port range '%s' is invalid, expected start-end", value)
+	}
+
+	start, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return nil, fmt.Errorf("port range '%s' is invalid, %s", value, err)
+	}
+
+	end, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+	if err != nil {
+		return nil, fmt.Errorf("port range '%s' is invalid, %s", value, err)
+	}
+
+	if start <= 0 || end > 65535 || start > end {
+		return nil, fmt.Errorf("port range '%s' is invalid, expected 0 < start <= end <= 65535", value)
+	}
+	return &PortRange{Start: start, End: end}, nil
+}
+
+// String is exported
+func (portRange *PortRange) String() string {
+
+	return strconv.Itoa(portRange.Start) + "-" + strconv.Itoa(portRange.End)
+}
+
+// isDynamicPort returns true if the meta config port binding at index is allocated by center.
+// meta placement enables dynamic ports, port binding has no published port and network is bridge or nat.
+func isDynamicPort(metaData *MetaData, index int) bool {
+
+	if !metaData.Placement.DynamicPorts || !isBridgeNetwork(metaData.Config) {
+		return false
+	}
+
+	if index < 0 || index >= len(metaData.Config.Ports) {
+		return false
+	}
+	return metaData.Config.Ports[index].PublicPort == 0
+}
+
+// resetDynamicPorts returns config with dynamic ports unallocated.
+// a migrated container config keeps its old allocated ports, they must be allocated again on the new engine.
+func resetDynamicPorts(metaData *MetaData, config models.Container) models.Container {
+
+	if !metaData.Placement.DynamicPorts || len(config.Ports) == 0 {
+		return config
+	}
+
+	ports := make([]models.PortBinding, len(config.Ports))
+	copy(ports, config.Ports)
+	for i := range ports {
+		if isDynamicPort(metaData, i) {
+			ports[i].PublicPort = 0
+		}
+	}
+	config.Ports = ports
+	return config
+}
+
+// dynamicPortBindings returns the port bindings of config allocated by center.
+func dynamicPortBindings(metaData *MetaData, config models.Container) []models.PortBinding {
+
+	portBindings := []models.PortBinding{}
+	for i, portBinding := range config.Ports {
+		if isDynamicPort(metaData, i) && portBinding.PublicPort != 0 {
+			portBindings = append(portBindings, portBinding)
+		}
+	}
+	return portBindings
+}
+
+// HostPortsAllocator is exported
+// allocates dynamic host ports on engines.
+// allocated ports are reserved until the container is created, so concurrent creates don't get the same port.
+type HostPortsAllocator struct {
+	sync.Mutex
+	portRange *PortRange
+	reserved  map[string]HostPorts
+}
+
+// NewHostPortsAllocator is exported
+func NewHostPortsAllocator(portRange *PortRange) *HostPortsAllocator {
+
+	return &HostPortsAllocator{
+		portRange: portRange,
+		reserved:  make(map[string]HostPorts),
+	}
+}
+
+// PortRange is exported
+// Return meta placement port range, empty or invalid port range is cluster default port range.
+func (allocator *HostPortsAllocator) PortRange(metaData *MetaData) *PortRange {
+
+	if strings.TrimSpace(metaData.Placement.PortRange) != "" {
+		if portRange, err := ParsePortRange(metaData.Placement.PortRange); err == nil {
+			return portRange
+		}
+	}
+	return allocator.portRange
+}
+
+// Allocate is exported
+// Return config with a free host port of engine assigned to each dynamic port binding.
+// ports bound by all engine containers, stopped containers included, running unmanaged containers and reserved ports are skipped.
+func (allocator *HostPortsAllocator) Allocate(engine *Engine, metaData *MetaData, config models.Container) (models.Container, error) {
+
+	dynamicIndexes := []int{}
+	for i := range config.Ports {
+		if isDynamicPort(metaData, i) && config.Ports[i].PublicPort == 0 {
+			dynamicIndexes = append(dynamicIndexes, i)
+		}
+	}
+
+	if len(dynamicIndexes) == 0 {
+		return config, nil
+	}
+
+	boundPorts := configHostPorts(config)
+	for _, container := range engine.Containers("") {
+		if container.Config != nil {
+			boundPorts.Add(configHostPorts(container.Config.Container))
+		}
+	}
+	boundPorts.Add(engine.unmanagedHostPorts())
+
+	allocator.Lock()
+	defer allocator.Unlock()
+	reserved, ret := allocator.reserved[engine.IP]
+	if !ret {
+		reserved = HostPorts{}
+	}
+	boundPorts.Add(reserved)
+
+	portRange := allocator.PortRange(metaData)
+	ports := make([]models.PortBinding, len(config.Ports))
+	copy(ports, config.Ports)
+	allocated := HostPorts{}
+	for _, index := range dynamicIndexes {
+		for port := portRange.Start; port <= portRange.End; port++ {
+			key := hostPortKey(ports[index].Type, port)
+			if !boundPorts[key] {
+				ports[index].PublicPort = port
+				boundPorts[key] = true
+				allocated[key] = true
+				break
+			}
+		}
+		if ports[index].PublicPort == 0 {
+			return config, ErrClusterDynamicPortsExhausted
+		}
+	}
+
+	reserved.Add(allocated)
+	allocator.reserved[engine.IP] = reserved
+	config.Ports = ports
+	return config, nil
+}
+
+// Release is exported
+// Release config host ports reserved on engine, the container is created or failed.
+func (allocator *HostPortsAllocator) Release(engine *Engine, config models.Container) {
+
+	allocator.Lock()
+	defer allocator.Unlock()
+	if reserved, ret := allocator.reserved[engine.IP]; ret {
+		for port := range configHostPorts(config) {
+			delete(reserved, port)
+		}
+		if len(reserved) == 0 {
+			delete(allocator.reserved, engine.IP)
+		}
+	}
+}
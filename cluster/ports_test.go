@@ -0,0 +1,86 @@
+package cluster
+
+import "github.com/humpback/common/models"
+
+import (
+	"testing"
+)
+
+func TestParsePortRange(t *testing.T) {
+
+	tests := []struct {
+		value string
+		valid bool
+		start int
+		end   int
+	}{
+		{"30000-32767", true, 30000, 32767},
+		{" 8000 - 8000 ", true, 8000, 8000},
+		{"1-65535", true, 1, 65535},
+		{"0-100", false, 0, 0},
+		{"100-65536", false, 0, 0},
+		{"200-100", false, 0, 0},
+		{"8000", false, 0, 0},
+		{"a-100", false, 0, 0},
+		{"100-b", false, 0, 0},
+		{"", false, 0, 0},
+	}
+
+	for _, test := range tests {
+		portRange, err := ParsePortRange(test.value)
+		if !test.valid {
+			if err == nil {
+				t.Errorf("ParsePortRange(%q) is valid, expected error", test.value)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParsePortRange(%q) error %s, expected valid", test.value, err)
+			continue
+		}
+		if portRange.Start != test.start || portRange.End != test.end {
+			t.Errorf("ParsePortRange(%q) is %s, expected %d-%d", test.value, portRange.String(), test.start, test.end)
+		}
+	}
+}
+
+func TestHostPortsAllocator(t *testing.T) {
+
+	portRange, _ := ParsePortRange("30000-30003")
+	allocator := NewHostPortsAllocator(portRange)
+	engine := newTestEngine("192.168.2.1", 8, 8192, nil)
+	engine.unmanagedPorts = HostPorts{"tcp/30001": true}
+	metaData := &MetaData{MetaBase: MetaBase{Config: models.Container{Name: "web", NetworkMode: "bridge", Ports: []models.PortBinding{{PrivatePort: 80}, {PrivatePort: 443, PublicPort: 8443}, {PrivatePort: 53, Type: "udp"}}}}}
+	metaData.Placement.DynamicPorts = true
+
+	tests := []struct {
+		ports []int
+		err   error
+	}{
+		//tcp/30001 is bound by an unmanaged container, udp ports are allocated apart from tcp ports.
+		{[]int{30000, 8443, 30000}, nil},
+		{[]int{30002, 8443, 30001}, nil},
+		{[]int{30003, 8443, 30002}, nil},
+		{[]int{0, 8443, 0}, ErrClusterDynamicPortsExhausted},
+	}
+
+	for index, test := range tests {
+		config, err := allocator.Allocate(engine, metaData, metaData.Config)
+		if err != test.err {
+			t.Errorf("Allocate %d error is %v, expected %v", index, err, test.err)
+			continue
+		}
+		for i, port := range test.ports {
+			if config.Ports[i].PublicPort != port {
+				t.Errorf("Allocate %d port %d is %d, expected %d", index, i, config.Ports[i].PublicPort, port)
+			}
+		}
+	}
+
+	//released ports are allocated again.
+	allocator.Release(engine, models.Container{NetworkMode: "bridge", Ports: []models.PortBinding{{PrivatePort: 80, PublicPort: 30002}}})
+	config, err := allocator.Allocate(engine, metaData, metaData.Config)
+	if err != nil || config.Ports[0].PublicPort != 30002 || config.Ports[2].PublicPort != 30003 {
+		t.Errorf("Allocate after release is %+v %v, expected port 30002 and udp port 30003", config.Ports, err)
+	}
+}
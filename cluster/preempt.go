@@ -0,0 +1,191 @@
+package cluster
+
+import "github.com/humpback/common/models"
+import "github.com/humpback/gounits/logger"
+
+import (
+	"sort"
+)
+
+// preemptVictims is exported
+// victim containers of an engine, sorted by meta priority, lowest first.
+type preemptVictims struct {
+	engine     *Engine
+	containers []*Container
+	metas      map[string]*MetaData
+}
+
+type priorityContainer struct {
+	container *Container
+	priority  int
+}
+
+type priorityContainers []*priorityContainer
+
+func (containers priorityContainers) Len() int {
+
+	return len(containers)
+}
+
+func (containers priorityContainers) Swap(i, j int) {
+
+	containers[i], containers[j] = containers[j], containers[i]
+}
+
+func (containers priorityContainers) Less(i, j int) bool {
+
+	return containers[i].priority < containers[j].priority
+}
+
+// preemptEngine is exported
+// When no placement engine has room for config, selects containers of strictly lower priority metas in the same group to evict.
+// Return victims of the engine which has room after eviction, nil if there is room already or no engine can make room.
+// victims are evicted by evictVictims, evicted metas are re-created by recovery loop.
+func (cluster *Cluster) preemptEngine(metaData *MetaData, engines []*Engine, groupEngines []*Engine, config models.Container) *preemptVictims {
+
+	lowerPriority := false
+	groupMetaData := cluster.configCache.GetGroupMetaData(metaData.GroupID)
+	for _, groupMeta := range groupMetaData {
+		if groupMeta.Priority < metaData.Priority {
+			lowerPriority = true
+			break
+		}
+	}
+
+	if !lowerPriority {
+		return nil
+	}
+
+	candidates := cluster.preemptCandidates(metaData, engines, groupMetaData)
+	//preemption frees requested resources only, so engines are weighted without actual usage.
+	if len(candidates) == 0 || len(selectWeightdEngines(candidates, config, false, nil)) > 0 {
+		return nil
+	}
+
+	if len(metaData.Placement.Preferences) > 0 {
+		preferences, err := ParsePreferences(metaData.Placement.Preferences)
+		if err != nil {
+			return nil
+		}
+		candidates = SpreadEngines(preferences, metaData.MetaID, candidates, groupEngines)
+	}
+
+	var victims *preemptVictims
+	for _, engine := range candidates {
+		if engineVictims := cluster.selectPreemptVictims(metaData, engine, config); engineVictims != nil {
+			if victims == nil || len(engineVictims.containers) < len(victims.containers) {
+				victims = engineVictims
+			}
+		}
+	}
+
+	if victims == nil {
+		logger.WARN("[#cluster#] meta %s priority %d, no lower priority containers to preempt.", metaData.MetaID, metaData.Priority)
+	}
+	return victims
+}
+
+// preemptCandidates returns active engines which match meta constraints and hard affinities, reverse antiaffinities included.
+// alloc or fail engines fallback of placement doesn't apply, an engine is never preempted against meta placement.
+func (cluster *Cluster) preemptCandidates(metaData *MetaData, engines []*Engine, groupMetaData []*MetaData) []*Engine {
+
+	candidates := []*Engine{}
+	constraints, err := ParseConstraints(metaData.Placement.Constraints)
+	if err != nil {
+		return candidates
+	}
+
+	for _, engine := range engines {
+		if engine.IsActive() && (len(constraints) == 0 || MatchConstraints(constraints, engine)) {
+			candidates = append(candidates, engine)
+		}
+	}
+
+	affinities, err := ParseAffinities(metaData.Placement.Affinities)
+	if err != nil {
+		return []*Engine{}
+	}
+	return AffinityEngines(affinities, metaData, groupMetaData, candidates)
+}
+
+// evictVictims is exported
+// evict victims containers in operations queue of victim metas, preemption fails if a victim meta has queued or running operations,
+// preemptor operation never waits behind operations of other metas. evict and preempt decisions are submitted as hook events.
+func (cluster *Cluster) evictVictims(metaData *MetaData, victims *preemptVictims) error {
+
+	metaContainers := make(map[string][]*Container)
+	for _, container := range victims.containers {
+		metaContainers[container.MetaID()] = append(metaContainers[container.MetaID()], container)
+	}
+
+	for metaid, containers := range metaContainers {
+		victimMeta := victims.metas[metaid]
+		operation := NewOperation(victimMeta.GroupID, victimMeta.Config.Name, victimMeta.MetaID, EvictOperation, func(operation *Operation) (interface{}, error) {
+			for _, container := range containers {
+				//container is removed by a prior operation of victim meta already.
+				if !victims.engine.HasContainer(container.Info.ID) {
+					continue
+				}
+				logger.WARN("[#cluster#] meta %s priority %d preempt engine %s, evict meta %s priority %d container %s.", metaData.MetaID, metaData.Priority, victims.engine.IP, victimMeta.MetaID, victimMeta.Priority, ShortContainerID(container.Info.ID))
+				if err := victims.engine.RemoveContainer(container.Info.ID); err != nil {
+					logger.ERROR("[#cluster#] engine %s evict container %s error:%s", victims.engine.IP, ShortContainerID(container.Info.ID), err.Error())
+					return nil, err
+				}
+			}
+			return nil, nil
+		})
+		if !cluster.operationsQueue.TrySubmit(operation) {
+			logger.WARN("[#cluster#] meta %s priority %d preempt engine %s, victim meta %s is busy.", metaData.MetaID, metaData.Priority, victims.engine.IP, victimMeta.MetaID)
+			return ErrClusterPreemptVictimBusy
+		}
+		if _, err := operation.Wait(); err != nil {
+			return err
+		}
+		cluster.submitHookEvent(victimMeta, EvictMetaEvent)
+	}
+	cluster.submitHookEvent(metaData, PreemptMetaEvent)
+	return nil
+}
+
+// selectPreemptVictims returns the fewest lowest priority containers to evict so engine has room for config.
+// Return nil if engine can't make room by evicting strictly lower priority containers of the same group, busy metas are never victims.
+func (cluster *Cluster) selectPreemptVictims(metaData *MetaData, engine *Engine, config models.Container) *preemptVictims {
+
+	candidates := priorityContainers{}
+	metas := make(map[string]*MetaData)
+	for _, container := range engine.Containers("") {
+		if container.Info.ContainerJSONBase == nil || container.Info.HostConfig == nil {
+			continue
+		}
+		victimMeta := cluster.GetMetaData(container.MetaID())
+		if victimMeta == nil || victimMeta.GroupID != metaData.GroupID || victimMeta.Priority >= metaData.Priority {
+			continue
+		}
+		if cluster.operationsQueue.Busy(victimMeta.GroupID, victimMeta.Config.Name, victimMeta.MetaID) {
+			continue
+		}
+		metas[victimMeta.MetaID] = victimMeta
+		candidates = append(candidates, &priorityContainer{container: container, priority: victimMeta.Priority})
+	}
+
+	sort.Stable(candidates)
+
+	usedCpus := engine.UsedCpus()
+	usedMemory := engine.UsedMemory() / 1024 / 1024
+	victims := &preemptVictims{engine: engine, containers: []*Container{}, metas: make(map[string]*MetaData)}
+	for _, candidate := range candidates {
+		container := candidate.container
+		if _, err := engineWeight(engine.TotalCpus(), engine.TotalMemory(), usedCpus, usedMemory, config); err == nil {
+			break
+		}
+		usedCpus = usedCpus - container.Info.HostConfig.CPUShares
+		usedMemory = usedMemory - container.Info.HostConfig.Memory/1024/1024
+		victims.containers = append(victims.containers, container)
+		victims.metas[container.MetaID()] = metas[container.MetaID()]
+	}
+
+	if _, err := engineWeight(engine.TotalCpus(), engine.TotalMemory(), usedCpus, usedMemory, config); err != nil {
+		return nil
+	}
+	return victims
+}
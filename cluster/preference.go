@@ -0,0 +1,113 @@
+package cluster
+
+import "github.com/humpback/humpback-center/cluster/types"
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Preference defines a spread placement preference.
+// containers of a meta are distributed evenly across the distinct values of a label.
+type Preference struct {
+	descriptor string
+	label      string
+	nodeLabel  bool
+}
+
+// ParsePreferences parses list of placement preferences.
+// spread descriptor is in the form of 'node.labels.key' or 'engine.labels.key'.
+func ParsePreferences(preferences []types.Preference) ([]Preference, error) {
+
+	prefs := []Preference{}
+	for _, p := range preferences {
+		descriptor := strings.TrimSpace(p.Spread.SpreadDescriptor)
+		if descriptor == "" {
+			continue
+		}
+
+		switch {
+		case len(descriptor) > len(NodeLabelsPrefix) && strings.EqualFold(descriptor[:len(NodeLabelsPrefix)], NodeLabelsPrefix):
+			prefs = append(prefs, Preference{descriptor: descriptor, label: descriptor[len(NodeLabelsPrefix):], nodeLabel: true})
+		case len(descriptor) > len(EngineLabelsPrefix) && strings.EqualFold(descriptor[:len(EngineLabelsPrefix)], EngineLabelsPrefix):
+			prefs = append(prefs, Preference{descriptor: descriptor, label: descriptor[len(EngineLabelsPrefix):], nodeLabel: false})
+		default:
+			return nil, fmt.Errorf("spread descriptor '%s' is invalid, expected prefix %s or %s", descriptor, NodeLabelsPrefix, EngineLabelsPrefix)
+		}
+	}
+	return prefs, nil
+}
+
+// Value returns the engine label value of the spread descriptor.
+// engine doesn't have this label, it's equivalent to an empty value.
+func (preference *Preference) Value(engine *Engine) string {
+
+	var labels map[string]string
+	if preference.nodeLabel {
+		labels = engine.NodeLabelsPairs()
+	} else {
+		labels = engine.EngineLabelsPairs()
+	}
+
+	if labels == nil {
+		return ""
+	}
+	// label itself is case sensitive
+	return labels[preference.label]
+}
+
+// SpreadEngines returns engines of the least used spread value.
+// metaid containers are counted on groupEngines for each value, nested preferences apply in order.
+// engines order is preserved, so the first engine of the result is still the best weighted engine.
+func SpreadEngines(preferences []Preference, metaid string, engines []*Engine, groupEngines []*Engine) []*Engine {
+
+	return spreadEngines(preferences, engines, groupEngines, func(engine *Engine) int {
+		return engine.ScheduledContainers(metaid)
+	})
+}
+
+// spreadEngines returns engines of the least used spread value, count returns engine meta containers count.
+func spreadEngines(preferences []Preference, engines []*Engine, groupEngines []*Engine, count func(engine *Engine) int) []*Engine {
+
+	if len(preferences) == 0 || len(engines) == 0 {
+		return engines
+	}
+
+	preference := preferences[0]
+	counts := map[string]int{}
+	for _, engine := range groupEngines {
+		if engine.IsHealthy() {
+			counts[preference.Value(engine)] += count(engine)
+		}
+	}
+
+	var (
+		found    bool
+		minValue string
+		minCount int
+	)
+
+	for _, engine := range engines {
+		value := preference.Value(engine)
+		if !found || counts[value] < minCount {
+			found = true
+			minValue = value
+			minCount = counts[value]
+		}
+	}
+
+	selectEngines := []*Engine{}
+	for _, engine := range engines {
+		if preference.Value(engine) == minValue {
+			selectEngines = append(selectEngines, engine)
+		}
+	}
+
+	spreadGroupEngines := []*Engine{}
+	for _, engine := range groupEngines {
+		if preference.Value(engine) == minValue {
+			spreadGroupEngines = append(spreadGroupEngines, engine)
+		}
+	}
+	return spreadEngines(preferences[1:], selectEngines, spreadGroupEngines, count)
+}
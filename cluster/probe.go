@@ -0,0 +1,188 @@
+package cluster
+
+import "github.com/humpback/gounits/logger"
+
+import (
+	"sync"
+	"time"
+)
+
+// engineProbe is a probe result counter of engine.
+type engineProbe struct {
+	failures    int
+	successes   int
+	unhealthyAt time.Time
+	migrated    bool
+}
+
+// EnginesProber is exported
+// probe agent api of cluster engines every interval, an engine is switched to unhealthy after failureThreshold
+// consecutive failed probes, and switched back to healthy after successThreshold consecutive succeeded probes.
+// containers of engine which is still unhealthy after gracePeriod are migrated to other engines.
+type EnginesProber struct {
+	sync.Mutex
+	Cluster          *Cluster
+	interval         time.Duration
+	timeout          time.Duration
+	failureThreshold int
+	successThreshold int
+	gracePeriod      time.Duration
+	probes           map[string]*engineProbe
+	stopCh           chan struct{}
+}
+
+// NewEnginesProber is exported
+// interval is 0, engines probe is disabled.
+func NewEnginesProber(interval time.Duration, timeout time.Duration, failureThreshold int, successThreshold int, gracePeriod time.Duration) *EnginesProber {
+
+	return &EnginesProber{
+		interval:         interval,
+		timeout:          timeout,
+		failureThreshold: failureThreshold,
+		successThreshold: successThreshold,
+		gracePeriod:      gracePeriod,
+		probes:           make(map[string]*engineProbe),
+		stopCh:           make(chan struct{}),
+	}
+}
+
+// SetCluster is exported
+func (prober *EnginesProber) SetCluster(cluster *Cluster) {
+
+	prober.Cluster = cluster
+}
+
+// Start is exported
+func (prober *EnginesProber) Start() {
+
+	if prober.interval > 0 {
+		logger.INFO("[#cluster#] engines prober interval %s, timeout %s, failures %d, successes %d, grace period %s.",
+			prober.interval, prober.timeout, prober.failureThreshold, prober.successThreshold, prober.gracePeriod)
+		go prober.doLoop()
+	}
+}
+
+// Close is exported
+func (prober *EnginesProber) Close() {
+
+	close(prober.stopCh)
+}
+
+// Remove is exported
+// engine is removed from cluster, clear engine probe.
+// return true if engine containers are already migrated by prober.
+func (prober *EnginesProber) Remove(engine *Engine) bool {
+
+	prober.Lock()
+	defer prober.Unlock()
+	if probe, ret := prober.probes[engine.IP]; ret {
+		delete(prober.probes, engine.IP)
+		return probe.migrated
+	}
+	return false
+}
+
+func (prober *EnginesProber) doLoop() {
+
+	for {
+		ticker := time.NewTicker(prober.interval)
+		select {
+		case <-ticker.C:
+			{
+				ticker.Stop()
+				engines := []*Engine{}
+				prober.Cluster.RLock()
+				for _, engine := range prober.Cluster.engines {
+					engines = append(engines, engine)
+				}
+				prober.Cluster.RUnlock()
+
+				wgroup := sync.WaitGroup{}
+				for _, engine := range engines {
+					if engine.IsHealthy() || engine.IsUnhealthy() {
+						wgroup.Add(1)
+						go func(e *Engine) {
+							prober.probeEngine(e)
+							wgroup.Done()
+						}(engine)
+					}
+				}
+				wgroup.Wait()
+			}
+		case <-prober.stopCh:
+			{
+				ticker.Stop()
+				return
+			}
+		}
+	}
+}
+
+// probeEngine pings engine agent api, and switches engine state by consecutive probe results.
+func (prober *EnginesProber) probeEngine(engine *Engine) {
+
+	err := engine.Ping(prober.timeout)
+	prober.Lock()
+	probe, ret := prober.probes[engine.IP]
+	if !ret {
+		probe = &engineProbe{}
+		prober.probes[engine.IP] = probe
+	}
+
+	if err != nil {
+		probe.successes = 0
+		probe.failures = probe.failures + 1
+	} else {
+		probe.failures = 0
+		probe.successes = probe.successes + 1
+	}
+
+	var (
+		unhealthy bool
+		recovered bool
+		migrate   bool
+		migrated  bool
+	)
+
+	if engine.IsHealthy() {
+		if err != nil && probe.failures >= prober.failureThreshold && engine.switchState(StateHealthy, StateUnhealthy) {
+			probe.unhealthyAt = time.Now()
+			probe.migrated = false
+			unhealthy = true
+		}
+	} else if engine.IsUnhealthy() {
+		if err == nil && probe.successes >= prober.successThreshold && engine.switchState(StateUnhealthy, StateHealthy) {
+			migrated = probe.migrated
+			probe.migrated = false
+			recovered = true
+		} else if err != nil && !probe.migrated && time.Since(probe.unhealthyAt) >= prober.gracePeriod {
+			probe.migrated = true
+			migrate = true
+		}
+	}
+	prober.Unlock()
+
+	if unhealthy {
+		logger.WARN("[#cluster#] engine %s %s unhealthy, %d probes failed, %s", engine.IP, engine.Name, prober.failureThreshold, err.Error())
+		prober.Cluster.NotifyGroupEnginesWatchEvent("cluster probe some engines unhealthy.", WatchEngines{NewWatchEngine(engine.IP, engine.Name, StateUnhealthy)})
+	}
+
+	if migrate {
+		logger.WARN("[#cluster#] engine %s %s unhealthy over grace period %s, migrate containers.", engine.IP, engine.Name, prober.gracePeriod)
+		prober.Cluster.migtatorCache.Evacuate(engine)
+	}
+
+	if recovered {
+		logger.INFO("[#cluster#] engine %s %s healthy, %d probes succeeded.", engine.IP, engine.Name, prober.successThreshold)
+		prober.Cluster.NotifyGroupEnginesWatchEvent("cluster probe some engines recovered.", WatchEngines{NewWatchEngine(engine.IP, engine.Name, StateHealthy)})
+		if migrated {
+			//cancel ready migrations, and expel original containers which are already migrated.
+			prober.Cluster.migtatorCache.Cancel(engine)
+			if err := engine.RefreshContainers(); err != nil {
+				logger.ERROR("[#cluster#] engine %s refresh containers error:%s", engine.IP, err.Error())
+				return
+			}
+			engine.ValidateContainers()
+		}
+	}
+}
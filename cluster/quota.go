@@ -0,0 +1,256 @@
+package cluster
+
+import "github.com/humpback/common/models"
+import "github.com/humpback/humpback-center/cluster/storage/dao"
+import "github.com/humpback/humpback-center/cluster/types"
+import "github.com/humpback/gounits/logger"
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// QuotaReservations is exported
+// group resources reserved by running create, update and recovery operations of metas.
+// a reserved meta is counted by its requested resources until its operation is finished,
+// so concurrent operations of different metas in a group can't exceed group quota together.
+type QuotaReservations struct {
+	sync.Mutex
+	groups map[string]map[string]*quotaReservation
+}
+
+type quotaReservation struct {
+	resources types.GroupResources
+	refs      int
+}
+
+// NewQuotaReservations is exported
+func NewQuotaReservations() *QuotaReservations {
+
+	return &QuotaReservations{
+		groups: make(map[string]map[string]*quotaReservation),
+	}
+}
+
+// quotaReservationKey returns reservation key of meta, a new meta is reserved by its name until it is created.
+func quotaReservationKey(metaid string, name string) string {
+
+	if metaid != "" {
+		return metaid
+	}
+	return "name/" + name
+}
+
+// GetGroupQuota is exported
+// Return group quota, nil if group has no quota.
+func (cluster *Cluster) GetGroupQuota(groupid string) (*types.GroupQuota, error) {
+
+	quota, err := cluster.storageDriver.QuotaStorage.QuotaByGroupID(groupid)
+	if err != nil {
+		if err == dao.ErrStorageObjectNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return quota.GroupQuota, nil
+}
+
+// SetGroupQuota is exported
+// Set group quota, a quota with all zero values removes the group quota.
+func (cluster *Cluster) SetGroupQuota(groupid string, quota types.GroupQuota) error {
+
+	if group := cluster.GetGroup(groupid); group == nil {
+		return ErrClusterGroupNotFound
+	}
+
+	if quota.MaxInstances < 0 || quota.MaxCPUShares < 0 || quota.MaxMemory < 0 {
+		return ErrClusterGroupQuotaInvalid
+	}
+
+	if quota.MaxInstances == 0 && quota.MaxCPUShares == 0 && quota.MaxMemory == 0 {
+		logger.INFO("[#cluster#] remove group %s quota.", groupid)
+		return cluster.removeGroupQuota(groupid)
+	}
+
+	logger.INFO("[#cluster#] set group %s quota, %+v", groupid, quota)
+	return cluster.storageDriver.QuotaStorage.SetQuota(groupid, &quota)
+}
+
+// GetGroupQuotaUsage is exported
+// Return group quota and current consumption of group containers.
+func (cluster *Cluster) GetGroupQuotaUsage(groupid string) (*types.GroupQuotaUsage, error) {
+
+	if group := cluster.GetGroup(groupid); group == nil {
+		return nil, ErrClusterGroupNotFound
+	}
+
+	quota, err := cluster.GetGroupQuota(groupid)
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.GroupQuotaUsage{
+		GroupID: groupid,
+		Quota:   quota,
+		Usage:   cluster.groupResources(groupid),
+	}, nil
+}
+
+func (cluster *Cluster) removeGroupQuota(groupid string) error {
+
+	err := cluster.storageDriver.QuotaStorage.DeleteQuota(groupid)
+	if err != nil && err != dao.ErrStorageObjectNotFound {
+		return err
+	}
+	return nil
+}
+
+// groupResources returns group containers consumption.
+func (cluster *Cluster) groupResources(groupid string) types.GroupResources {
+
+	resources := types.GroupResources{}
+	groupMetaData := cluster.configCache.GetGroupMetaData(groupid)
+	for _, metaData := range groupMetaData {
+		if count := cluster.configCache.GetMetaDataBaseConfigsCount(metaData.MetaID); count > 0 {
+			resources = addGroupResources(resources, count, metaData.Config)
+		}
+	}
+	return resources
+}
+
+// checkGroupQuota returns ErrClusterGroupQuotaExceeded if group consumption exceeds group quota,
+// after metaid containers are replaced by instances of config, resources reserved by other metas are counted.
+// metaid is empty string for new meta which is named name, a resource already over quota is accepted if it doesn't grow.
+func (cluster *Cluster) checkGroupQuota(groupid string, metaid string, name string, instances int, config models.Container) error {
+
+	cluster.quotaReserved.Lock()
+	defer cluster.quotaReserved.Unlock()
+	return cluster.checkGroupQuotaReserved(groupid, metaid, name, instances, config)
+}
+
+// reserveGroupQuota checks group quota, then reserves instances of config for meta until releaseGroupQuota is called.
+func (cluster *Cluster) reserveGroupQuota(groupid string, metaid string, name string, instances int, config models.Container) error {
+
+	reservations := cluster.quotaReserved
+	reservations.Lock()
+	defer reservations.Unlock()
+	if err := cluster.checkGroupQuotaReserved(groupid, metaid, name, instances, config); err != nil {
+		return err
+	}
+
+	key := quotaReservationKey(metaid, name)
+	groupReservations, ret := reservations.groups[groupid]
+	if !ret {
+		groupReservations = make(map[string]*quotaReservation)
+		reservations.groups[groupid] = groupReservations
+	}
+
+	reservation, ret := groupReservations[key]
+	if !ret {
+		reservation = &quotaReservation{}
+		groupReservations[key] = reservation
+	}
+	reservation.resources = addGroupResources(types.GroupResources{}, instances, config)
+	reservation.refs = reservation.refs + 1
+	return nil
+}
+
+// releaseGroupQuota releases group quota reserved for meta, the operation of meta is finished.
+func (cluster *Cluster) releaseGroupQuota(groupid string, metaid string, name string) {
+
+	reservations := cluster.quotaReserved
+	reservations.Lock()
+	defer reservations.Unlock()
+	key := quotaReservationKey(metaid, name)
+	if groupReservations, ret := reservations.groups[groupid]; ret {
+		if reservation, ret := groupReservations[key]; ret {
+			if reservation.refs = reservation.refs - 1; reservation.refs <= 0 {
+				delete(groupReservations, key)
+			}
+		}
+		if len(groupReservations) == 0 {
+			delete(reservations.groups, groupid)
+		}
+	}
+}
+
+// checkGroupQuotaReserved is checkGroupQuota, quota reservations lock is held by caller.
+func (cluster *Cluster) checkGroupQuotaReserved(groupid string, metaid string, name string, instances int, config models.Container) error {
+
+	quota, err := cluster.GetGroupQuota(groupid)
+	if err != nil {
+		logger.ERROR("[#cluster#] group %s quota read error, %s", groupid, err.Error())
+		return err
+	}
+
+	if quota == nil {
+		return nil
+	}
+
+	//other reserved metas are counted by reserved resources, instead of their current containers.
+	key := quotaReservationKey(metaid, name)
+	groupReservations := cluster.quotaReserved.groups[groupid]
+	requested := types.GroupResources{}
+	for reservedKey, reservation := range groupReservations {
+		if reservedKey != key {
+			requested = sumGroupResources(requested, reservation.resources)
+		}
+	}
+
+	usage := types.GroupResources{}
+	groupMetaData := cluster.configCache.GetGroupMetaData(groupid)
+	for _, metaData := range groupMetaData {
+		count := cluster.configCache.GetMetaDataBaseConfigsCount(metaData.MetaID)
+		if count <= 0 {
+			continue
+		}
+		usage = addGroupResources(usage, count, metaData.Config)
+		if metaData.MetaID == metaid {
+			continue
+		}
+		if _, ret := groupReservations[metaData.MetaID]; ret {
+			continue
+		}
+		if _, ret := groupReservations[quotaReservationKey("", metaData.Config.Name)]; ret {
+			continue
+		}
+		requested = addGroupResources(requested, count, metaData.Config)
+	}
+
+	requested = addGroupResources(requested, instances, config)
+	exceeded := []string{}
+	if quota.MaxInstances > 0 && requested.Instances > quota.MaxInstances && requested.Instances > usage.Instances {
+		exceeded = append(exceeded, fmt.Sprintf("instances %d/%d", requested.Instances, quota.MaxInstances))
+	}
+
+	if quota.MaxCPUShares > 0 && requested.CPUShares > quota.MaxCPUShares && requested.CPUShares > usage.CPUShares {
+		exceeded = append(exceeded, fmt.Sprintf("cpushares %d/%d", requested.CPUShares, quota.MaxCPUShares))
+	}
+
+	if quota.MaxMemory > 0 && requested.Memory > quota.MaxMemory && requested.Memory > usage.Memory {
+		exceeded = append(exceeded, fmt.Sprintf("memory %dMB/%dMB", requested.Memory, quota.MaxMemory))
+	}
+
+	if len(exceeded) > 0 {
+		logger.ERROR("[#cluster#] group %s quota exceeded, %s", groupid, strings.Join(exceeded, ", "))
+		return ErrClusterGroupQuotaExceeded
+	}
+	return nil
+}
+
+func sumGroupResources(resources types.GroupResources, other types.GroupResources) types.GroupResources {
+
+	resources.Instances = resources.Instances + other.Instances
+	resources.CPUShares = resources.CPUShares + other.CPUShares
+	resources.Memory = resources.Memory + other.Memory
+	return resources
+}
+
+func addGroupResources(resources types.GroupResources, instances int, config models.Container) types.GroupResources {
+
+	resources.Instances = resources.Instances + instances
+	resources.CPUShares = resources.CPUShares + int64(instances)*config.CPUShares
+	resources.Memory = resources.Memory + int64(instances)*config.Memory
+	return resources
+}
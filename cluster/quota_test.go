@@ -0,0 +1,57 @@
+package cluster
+
+import "github.com/humpback/common/models"
+import "github.com/humpback/humpback-center/cluster/types"
+
+import (
+	"testing"
+)
+
+func TestReserveGroupQuota(t *testing.T) {
+
+	cluster := newTestCluster(t, newTestEngine("192.168.2.1", 8, 8192, nil))
+	if err := cluster.SetGroupQuota(testGroupID, types.GroupQuota{MaxInstances: 4, MaxMemory: 1024}); err != nil {
+		t.Fatalf("SetGroupQuota error %s", err)
+	}
+
+	config := models.Container{Name: "web", Memory: 256}
+	tests := []struct {
+		reserve   bool
+		metaid    string
+		name      string
+		instances int
+		err       error
+	}{
+		//concurrent operations of different metas are counted together until they are released.
+		{true, "", "web", 2, nil},
+		{true, "", "api", 2, nil},
+		{true, "", "job", 1, ErrClusterGroupQuotaExceeded},
+		{true, "", "web", 3, ErrClusterGroupQuotaExceeded},
+		{false, "", "api", 0, nil},
+		{true, "", "job", 2, nil},
+		{true, "meta-1", "web", 1, ErrClusterGroupQuotaExceeded},
+		{false, "", "web", 0, nil},
+		{true, "meta-1", "web", 2, nil},
+	}
+
+	for index, test := range tests {
+		if !test.reserve {
+			cluster.releaseGroupQuota(testGroupID, test.metaid, test.name)
+			continue
+		}
+		err := cluster.reserveGroupQuota(testGroupID, test.metaid, test.name, test.instances, config)
+		if err != test.err {
+			t.Errorf("reserveGroupQuota %d %s %d instances error is %v, expected %v", index, test.name, test.instances, err, test.err)
+		}
+	}
+
+	if err := cluster.checkGroupQuota(testGroupID, "", "api", 1, config); err != ErrClusterGroupQuotaExceeded {
+		t.Errorf("checkGroupQuota of reserved group error is %v, expected %s", err, ErrClusterGroupQuotaExceeded)
+	}
+
+	cluster.releaseGroupQuota(testGroupID, "", "job")
+	cluster.releaseGroupQuota(testGroupID, "meta-1", "web")
+	if _, ret := cluster.quotaReserved.groups[testGroupID]; ret {
+		t.Errorf("released group reservations are kept")
+	}
+}
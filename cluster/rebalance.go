@@ -0,0 +1,281 @@
+package cluster
+
+import "github.com/humpback/humpback-center/cluster/types"
+import "github.com/humpback/gounits/logger"
+
+import (
+	"fmt"
+	"time"
+)
+
+// defaultRebalancePause is the default interval between two rebalance moves.
+var defaultRebalancePause = time.Duration(time.Second * 10)
+
+// rebalanceEngine is a rebalance layout snapshot of engine.
+type rebalanceEngine struct {
+	engine     *Engine
+	eligible   bool
+	count      int
+	usedCpus   int64
+	usedMemory int64
+	containers []*Container
+}
+
+// RebalanceContainers is exported
+// Compute an even layout of meta containers on group engines and move containers one by one in background.
+// pause is the interval between two moves, 0 is default pause.
+// returned moves are planned at request, rebalance operation computes moves again after prior operations of meta.
+func (cluster *Cluster) RebalanceContainers(metaid string, pause time.Duration) (*types.RebalanceContainers, error) {
+
+	metaData, engines, err := cluster.validateMetaData(metaid)
+	if err != nil {
+		logger.ERROR("[#cluster#] rebalance meta %s error, %s", metaid, err.Error())
+		return nil, err
+	}
+
+	if pause <= 0 {
+		pause = defaultRebalancePause
+	}
+
+	rebalanceContainers := cluster.rebalanceMoves(metaData, engines, pause)
+	operation := cluster.submitRebalanceContainers(metaData, pause)
+	rebalanceContainers.OperationID = operation.ID
+	return rebalanceContainers, nil
+}
+
+// RebalanceGroupContainers is exported
+// Rebalance every meta of group, metas are rebalanced one by one in background.
+// metas which are migrating are skipped.
+func (cluster *Cluster) RebalanceGroupContainers(groupid string, pause time.Duration) ([]*types.RebalanceContainers, error) {
+
+	if group := cluster.GetGroup(groupid); group == nil {
+		return nil, ErrClusterGroupNotFound
+	}
+
+	if pause <= 0 {
+		pause = defaultRebalancePause
+	}
+
+	metaDatas := make(map[string]*MetaData)
+	groupRebalanceContainers := []*types.RebalanceContainers{}
+	groupMetaData := cluster.configCache.GetGroupMetaData(groupid)
+	for _, groupMeta := range groupMetaData {
+		metaData, engines, err := cluster.validateMetaData(groupMeta.MetaID)
+		if err != nil {
+			logger.WARN("[#cluster#] rebalance group %s skip meta %s, %s", groupid, groupMeta.MetaID, err.Error())
+			continue
+		}
+		rebalanceContainers := cluster.rebalanceMoves(metaData, engines, pause)
+		if len(rebalanceContainers.Moves) > 0 {
+			metaDatas[metaData.MetaID] = metaData
+		}
+		groupRebalanceContainers = append(groupRebalanceContainers, rebalanceContainers)
+	}
+
+	if len(metaDatas) > 0 {
+		go func() {
+			for _, rebalanceContainers := range groupRebalanceContainers {
+				if metaData, ret := metaDatas[rebalanceContainers.MetaID]; ret {
+					cluster.submitRebalanceContainers(metaData, pause).Wait()
+				}
+			}
+		}()
+	}
+	return groupRebalanceContainers, nil
+}
+
+// rebalanceMoves returns moves of an even layout, a container is moved from the engine which has most meta containers
+// to the eligible engine which has least meta containers, until their difference is at most 1.
+// eligible engines are active and match meta placement platforms, constraints, hard affinities, max per engine, host ports and resources.
+// target is an engine of the least used spread value of meta preferences, so a move doesn't break spread.
+func (cluster *Cluster) rebalanceMoves(metaData *MetaData, engines []*Engine, pause time.Duration) *types.RebalanceContainers {
+
+	rebalanceContainers := &types.RebalanceContainers{
+		GroupID: metaData.GroupID,
+		MetaID:  metaData.MetaID,
+		Pause:   pause.String(),
+		Moves:   []*types.RebalanceMove{},
+	}
+
+	healthyEngines := []*Engine{}
+	for _, engine := range engines {
+		if engine.IsHealthy() {
+			healthyEngines = append(healthyEngines, engine)
+		}
+	}
+
+	eligibleEngines := cluster.selectPlatformEngines(healthyEngines, nil, metaData.Placement.Platforms)
+	if constraints, err := ParseConstraints(metaData.Placement.Constraints); err == nil && len(constraints) > 0 {
+		matchEngines := []*Engine{}
+		for _, engine := range eligibleEngines {
+			if MatchConstraints(constraints, engine) {
+				matchEngines = append(matchEngines, engine)
+			}
+		}
+		eligibleEngines = matchEngines
+	}
+
+	if affinities, err := ParseAffinities(metaData.Placement.Affinities); err == nil {
+		groupMetaData := cluster.configCache.GetGroupMetaData(metaData.GroupID)
+		eligibleEngines = AffinityEngines(affinities, metaData, groupMetaData, eligibleEngines)
+	}
+
+	preferences, err := ParsePreferences(metaData.Placement.Preferences)
+	if err != nil {
+		logger.WARN("[#cluster#] rebalance meta %s preferences invalid, %s", metaData.MetaID, err.Error())
+		return rebalanceContainers
+	}
+
+	hostPorts := configHostPorts(metaData.Config)
+	rEngines := []*rebalanceEngine{}
+	layout := make(map[*Engine]*rebalanceEngine)
+	for _, engine := range healthyEngines {
+		usedCpus, usedMemory := engineUsed(engine, cluster.usageWeight)
+		rEngine := &rebalanceEngine{
+			engine:     engine,
+			eligible:   engine.IsActive() && containsEngine(eligibleEngines, engine),
+			usedCpus:   usedCpus,
+			usedMemory: usedMemory,
+			containers: []*Container{},
+		}
+		if reduceEngines := selectReduceEngines(metaData, []*Engine{engine}); len(reduceEngines) > 0 {
+			sortReduceEngines(metaData, reduceEngines)
+			for _, reduceEngine := range reduceEngines {
+				rEngine.containers = append(rEngine.containers, reduceEngine.ReduceContainer())
+			}
+		}
+		rEngine.count = len(rEngine.containers)
+		if rEngine.eligible && len(hostPorts) > 0 && len(engine.HostPorts().Conflicts(hostPorts)) > 0 {
+			rEngine.eligible = false
+		}
+		rEngines = append(rEngines, rEngine)
+		layout[engine] = rEngine
+	}
+
+	for {
+		var source, target *rebalanceEngine
+		for _, rEngine := range rEngines {
+			if len(rEngine.containers) > 0 && (source == nil || rEngine.count > source.count) {
+				source = rEngine
+			}
+		}
+
+		if source == nil {
+			break
+		}
+
+		candidates := []*Engine{}
+		for _, rEngine := range rEngines {
+			if rEngine == source || !rEngine.eligible {
+				continue
+			}
+			if metaData.Placement.MaxPerEngine > 0 && rEngine.count >= metaData.Placement.MaxPerEngine {
+				continue
+			}
+			if _, err := engineWeight(rEngine.engine.TotalCpus(), rEngine.engine.TotalMemory(), rEngine.usedCpus, rEngine.usedMemory, metaData.Config); err != nil {
+				continue
+			}
+			candidates = append(candidates, rEngine.engine)
+		}
+
+		//source container is counted out of layout, so spread values are compared after the move.
+		source.count = source.count - 1
+		candidates = spreadEngines(preferences, candidates, healthyEngines, func(engine *Engine) int {
+			return layout[engine].count
+		})
+		source.count = source.count + 1
+		for _, engine := range candidates {
+			if rEngine := layout[engine]; target == nil || rEngine.count < target.count {
+				target = rEngine
+			}
+		}
+
+		if target == nil || source.count-target.count <= 1 {
+			break
+		}
+
+		container := source.containers[0]
+		source.containers = source.containers[1:]
+		source.count = source.count - 1
+		target.count = target.count + 1
+		target.usedCpus = target.usedCpus + metaData.Config.CPUShares
+		target.usedMemory = target.usedMemory + metaData.Config.Memory
+		if len(hostPorts) > 0 { //host ports are bound on target now.
+			target.eligible = false
+		}
+		rebalanceContainers.Moves = append(rebalanceContainers.Moves, &types.RebalanceMove{
+			ContainerID: container.Info.ID,
+			Name:        container.Config.Name,
+			From:        source.engine.IP,
+			To:          target.engine.IP,
+		})
+	}
+	return rebalanceContainers
+}
+
+// submitRebalanceContainers queues rebalance operation of meta, moves are computed and executed after prior operations of meta.
+func (cluster *Cluster) submitRebalanceContainers(metaData *MetaData, pause time.Duration) *Operation {
+
+	return cluster.submitOperation(metaData, RebalanceOperation, func(operation *Operation) (interface{}, error) {
+		metaData, engines, err := cluster.validateMetaData(operation.MetaID())
+		if err != nil {
+			return nil, err
+		}
+
+		rebalanceContainers := cluster.rebalanceMoves(metaData, engines, pause)
+		rebalanceContainers.OperationID = operation.ID
+		if len(rebalanceContainers.Moves) == 0 {
+			logger.INFO("[#cluster#] rebalance meta %s, layout is even.", metaData.MetaID)
+			return rebalanceContainers, nil
+		}
+		return rebalanceContainers, cluster.rebalanceContainers(metaData, rebalanceContainers.Moves, pause)
+	})
+}
+
+// rebalanceContainers executes moves one by one, stops at the first failed move.
+func (cluster *Cluster) rebalanceContainers(metaData *MetaData, moves []*types.RebalanceMove, pause time.Duration) error {
+
+	var err error
+	logger.INFO("[#cluster#] rebalance meta %s, %d moves.", metaData.MetaID, len(moves))
+	for i, move := range moves {
+		if i > 0 {
+			time.Sleep(pause)
+		}
+		if err = cluster.rebalanceContainer(metaData, move); err != nil {
+			logger.ERROR("[#cluster#] rebalance meta %s container %s error, %s", metaData.MetaID, ShortContainerID(move.ContainerID), err.Error())
+			break
+		}
+	}
+	cluster.submitHookEvent(metaData, RebalanceMetaEvent)
+	return err
+}
+
+// rebalanceContainer creates a new container on move target engine, then removes original container.
+func (cluster *Cluster) rebalanceContainer(metaData *MetaData, move *types.RebalanceMove) error {
+
+	source := cluster.GetEngine(move.From)
+	if source == nil || !source.IsHealthy() {
+		return fmt.Errorf("engine %s is not healthy", move.From)
+	}
+
+	target := cluster.GetEngine(move.To)
+	if target == nil || !target.IsActive() {
+		return fmt.Errorf("engine %s is not active", move.To)
+	}
+
+	container := source.Container(move.ContainerID)
+	if container == nil || container.BaseConfig == nil {
+		return fmt.Errorf("container not found")
+	}
+
+	config := container.BaseConfig.Container
+	config.ID = "" //create a new container
+	priorities := &EnginePriorities{Engines: map[string]*Engine{move.ContainerID: target}}
+	engine, newContainer, err := cluster.createContainer(metaData, NewEnginesFilter(), priorities, config)
+	if err != nil {
+		return err
+	}
+
+	logger.INFO("[#cluster#] rebalance container %s > %s to %s", ShortContainerID(move.ContainerID), ShortContainerID(newContainer.Info.ID), engine.IP)
+	return source.RemoveContainer(move.ContainerID)
+}
@@ -1,10 +1,31 @@
 package cluster
 
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// IndexReducePolicy is exported
+	IndexReducePolicy = "index"
+	// NewestReducePolicy is exported
+	NewestReducePolicy = "newest"
+	// OldestReducePolicy is exported
+	OldestReducePolicy = "oldest"
+	// UnhealthyReducePolicy is exported
+	UnhealthyReducePolicy = "unhealthy"
+	// SpreadReducePolicy is exported
+	SpreadReducePolicy = "spread"
+)
+
 // ReduceEngine is exported
+// spreadCounts is meta containers count of container engine spread values, for each nested preference.
 type ReduceEngine struct {
-	metaid    string
-	engine    *Engine
-	container *Container
+	metaid       string
+	engine       *Engine
+	container    *Container
+	spreadCounts []int
 }
 
 // Containers is exported
@@ -29,6 +50,17 @@ func (reduce *ReduceEngine) Engine() *Engine {
 	return reduce.engine
 }
 
+// ValidateReducePolicy is exported
+// empty policy reduces containers of the engine which has most meta containers first.
+func ValidateReducePolicy(policy string) error {
+
+	switch strings.ToLower(strings.TrimSpace(policy)) {
+	case "", IndexReducePolicy, NewestReducePolicy, OldestReducePolicy, UnhealthyReducePolicy, SpreadReducePolicy:
+		return nil
+	}
+	return ErrClusterReducePolicyInvalid
+}
+
 type reduceEngines []*ReduceEngine
 
 func (engines reduceEngines) Len() int {
@@ -46,20 +78,145 @@ func (engines reduceEngines) Less(i, j int) bool {
 	return len(engines[i].Containers()) > len(engines[j].Containers())
 }
 
-func selectReduceEngines(metaid string, engines []*Engine) reduceEngines {
+// reducePolicyEngines sorts reduce engines by policy, the first container is reduced first.
+// crash-looping containers are always reduced first, ties are broken by engine meta containers count and higher index.
+type reducePolicyEngines struct {
+	reduceEngines
+	policy string
+}
+
+func (engines *reducePolicyEngines) Less(i, j int) bool {
+
+	a, b := engines.reduceEngines[i], engines.reduceEngines[j]
+	if crashA, crashB := isCrashLooping(a.container), isCrashLooping(b.container); crashA != crashB {
+		return crashA
+	}
+
+	switch engines.policy {
+	case IndexReducePolicy:
+		if a.container.Index() != b.container.Index() {
+			return a.container.Index() > b.container.Index()
+		}
+	case NewestReducePolicy, OldestReducePolicy:
+		createdA, createdB := containerCreated(a.container), containerCreated(b.container)
+		if !createdA.Equal(createdB) {
+			if engines.policy == NewestReducePolicy {
+				return createdA.After(createdB)
+			}
+			return createdA.Before(createdB)
+		}
+	case UnhealthyReducePolicy:
+		if unhealthyA, unhealthyB := isUnhealthy(a.container), isUnhealthy(b.container); unhealthyA != unhealthyB {
+			return unhealthyA
+		}
+	case SpreadReducePolicy:
+		for k := 0; k < len(a.spreadCounts) && k < len(b.spreadCounts); k++ {
+			if a.spreadCounts[k] != b.spreadCounts[k] {
+				return a.spreadCounts[k] > b.spreadCounts[k]
+			}
+		}
+	}
+
+	if countA, countB := len(a.Containers()), len(b.Containers()); countA != countB {
+		return countA > countB
+	}
+	return a.container.Index() > b.container.Index()
+}
+
+// sortReduceEngines sorts reduce engines by meta placement reduce policy.
+func sortReduceEngines(metaData *MetaData, engines reduceEngines) {
+
+	policy := strings.ToLower(strings.TrimSpace(metaData.Placement.ReducePolicy))
+	sort.Stable(&reducePolicyEngines{reduceEngines: engines, policy: policy})
+}
+
+// selectReduceEngines returns a reduce engine of each meta container on healthy engines.
+func selectReduceEngines(metaData *MetaData, engines []*Engine) reduceEngines {
+
+	var preferences []Preference
+	if strings.EqualFold(strings.TrimSpace(metaData.Placement.ReducePolicy), SpreadReducePolicy) {
+		preferences, _ = ParsePreferences(metaData.Placement.Preferences)
+	}
 
 	out := reduceEngines{}
 	for _, engine := range engines {
 		if engine.IsHealthy() {
-			containers := engine.Containers(metaid)
-			if len(containers) > 0 {
+			containers := engine.Containers(metaData.MetaID)
+			spreadCounts := spreadReduceCounts(preferences, metaData.MetaID, engine, engines)
+			for _, container := range containers {
 				out = append(out, &ReduceEngine{
-					engine:    engine,
-					metaid:    metaid,
-					container: containers[0],
+					engine:       engine,
+					metaid:       metaData.MetaID,
+					container:    container,
+					spreadCounts: spreadCounts,
 				})
 			}
 		}
 	}
 	return out
 }
+
+// spreadReduceCounts returns metaid containers count of engine spread values on groupEngines.
+// the count of each nested preference only includes engines of the same previous values.
+func spreadReduceCounts(preferences []Preference, metaid string, engine *Engine, groupEngines []*Engine) []int {
+
+	counts := []int{}
+	for i := range preferences {
+		count := 0
+		for _, groupEngine := range groupEngines {
+			if !groupEngine.IsHealthy() {
+				continue
+			}
+			matched := true
+			for _, preference := range preferences[:i+1] {
+				if preference.Value(groupEngine) != preference.Value(engine) {
+					matched = false
+					break
+				}
+			}
+			if matched {
+				count = count + len(groupEngine.Containers(metaid))
+			}
+		}
+		counts = append(counts, count)
+	}
+	return counts
+}
+
+// isCrashLooping returns true if docker is restarting container, or container exited after restarts.
+func isCrashLooping(container *Container) bool {
+
+	if container.Info.ContainerJSONBase == nil || container.Info.State == nil {
+		return false
+	}
+
+	state := container.Info.State
+	return state.Restarting || (!state.Running && container.Info.RestartCount > 0)
+}
+
+// isUnhealthy returns true if container is not running or its health check fails.
+func isUnhealthy(container *Container) bool {
+
+	if container.Info.ContainerJSONBase == nil || container.Info.State == nil {
+		return true
+	}
+
+	state := container.Info.State
+	if !state.Running || state.Dead || state.OOMKilled {
+		return true
+	}
+	return state.Health != nil && state.Health.Status == "unhealthy"
+}
+
+func containerCreated(container *Container) time.Time {
+
+	if container.Info.ContainerJSONBase == nil {
+		return time.Time{}
+	}
+
+	created, err := time.Parse(time.RFC3339Nano, container.Info.Created)
+	if err != nil {
+		return time.Time{}
+	}
+	return created
+}
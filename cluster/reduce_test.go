@@ -0,0 +1,72 @@
+package cluster
+
+import "github.com/docker/docker/api/types"
+
+import (
+	"sort"
+	"testing"
+)
+
+type testReduceContainer struct {
+	index    int
+	created  string
+	state    types.ContainerState
+	restarts int
+	spread   []int
+}
+
+func TestReducePolicyEngines(t *testing.T) {
+
+	running := types.ContainerState{Running: true}
+	exited := types.ContainerState{Running: false}
+	unhealthy := types.ContainerState{Running: true, Health: &types.Health{Status: "unhealthy"}}
+	containers := []testReduceContainer{
+		{1, "2019-03-01T10:00:00Z", running, 0, []int{3, 2}},
+		{2, "2019-03-03T10:00:00Z", running, 0, []int{3, 1}},
+		{3, "2019-03-02T10:00:00Z", unhealthy, 0, []int{1, 1}},
+		{4, "2019-03-04T10:00:00Z", exited, 0, []int{1, 1}},
+		{5, "2019-02-01T10:00:00Z", exited, 3, []int{1, 1}},
+	}
+
+	tests := []struct {
+		policy  string
+		indexes []int
+	}{
+		{"", []int{5, 4, 3, 2, 1}},
+		{IndexReducePolicy, []int{5, 4, 3, 2, 1}},
+		{NewestReducePolicy, []int{5, 4, 2, 3, 1}},
+		{OldestReducePolicy, []int{5, 1, 3, 2, 4}},
+		{UnhealthyReducePolicy, []int{5, 4, 3, 2, 1}},
+		{SpreadReducePolicy, []int{5, 1, 2, 4, 3}},
+	}
+
+	for _, test := range tests {
+		engines := reduceEngines{}
+		for _, c := range containers {
+			state := c.state
+			engines = append(engines, &ReduceEngine{
+				container: &Container{
+					BaseConfig: &ContainerBaseConfig{Index: c.index},
+					Info:       types.ContainerJSON{ContainerJSONBase: &types.ContainerJSONBase{Created: c.created, RestartCount: c.restarts, State: &state}},
+				},
+				spreadCounts: c.spread,
+			})
+		}
+		//crash looping container is reduced first of every policy.
+		sort.Stable(&reducePolicyEngines{reduceEngines: engines, policy: test.policy})
+		indexes := []int{}
+		for _, engine := range engines {
+			indexes = append(indexes, engine.container.Index())
+		}
+		if len(indexes) != len(test.indexes) {
+			t.Errorf("reduce policy %q order is %v, expected %v", test.policy, indexes, test.indexes)
+			continue
+		}
+		for i := range indexes {
+			if indexes[i] != test.indexes[i] {
+				t.Errorf("reduce policy %q order is %v, expected %v", test.policy, indexes, test.indexes)
+				break
+			}
+		}
+	}
+}
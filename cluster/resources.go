@@ -0,0 +1,71 @@
+package cluster
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// OvercommitNodeLabel is exported
+	// engine overcommit ratio, overrides cluster overcommit driver opt, like '0.2'.
+	OvercommitNodeLabel = "humpback.overcommit"
+	// ReservedCpusNodeLabel is exported
+	// engine cpus reserved for system, subtracted from engine cpus, like '2'.
+	ReservedCpusNodeLabel = "humpback.reserved.cpus"
+	// ReservedMemoryNodeLabel is exported
+	// engine memory reserved for system, subtracted from engine memory, MB without unit, otherwise like '4g', '512m'.
+	ReservedMemoryNodeLabel = "humpback.reserved.memory"
+)
+
+// EngineResources is exported
+// engine capacity overrides of node labels.
+// overcommitRatio is percent, hasOvercommit is false if engine uses cluster overcommit ratio.
+type EngineResources struct {
+	overcommitRatio int64
+	hasOvercommit   bool
+	reservedCpus    int64
+	reservedMemory  int64
+}
+
+// ParseEngineResources is exported
+// parses reserved node labels of engine capacity, other labels are ignored.
+func ParseEngineResources(labels map[string]string) (*EngineResources, error) {
+
+	resources := &EngineResources{}
+	if value, ret := labels[OvercommitNodeLabel]; ret {
+		ratio, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || ratio <= float64(-1) {
+			return nil, fmt.Errorf("node label %s value '%s' is invalid, should be larger than -1", OvercommitNodeLabel, value)
+		}
+		resources.overcommitRatio = int64(ratio * 100)
+		resources.hasOvercommit = true
+	}
+
+	if value, ret := labels[ReservedCpusNodeLabel]; ret {
+		cpus, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil || cpus < 0 {
+			return nil, fmt.Errorf("node label %s value '%s' is invalid, should be larger than or equal to 0", ReservedCpusNodeLabel, value)
+		}
+		resources.reservedCpus = cpus
+	}
+
+	if value, ret := labels[ReservedMemoryNodeLabel]; ret {
+		memory, err := parseMemoryValue(strings.TrimSpace(value))
+		if err != nil || memory < 0 {
+			return nil, fmt.Errorf("node label %s value '%s' is invalid, expected MB or size like '4g'", ReservedMemoryNodeLabel, value)
+		}
+		resources.reservedMemory = int64(memory)
+	}
+	return resources, nil
+}
+
+// engineCapacity returns total of capacity after reserved is subtracted and overcommit ratio(percent) is applied.
+func engineCapacity(capacity int64, reserved int64, overcommitRatio int64) int64 {
+
+	capacity = capacity - reserved
+	if capacity < 0 {
+		return 0
+	}
+	return capacity + (capacity * overcommitRatio / 100)
+}
@@ -0,0 +1,76 @@
+package cluster
+
+import (
+	"testing"
+)
+
+func TestParseEngineResources(t *testing.T) {
+
+	tests := []struct {
+		labels          map[string]string
+		valid           bool
+		overcommitRatio int64
+		hasOvercommit   bool
+		reservedCpus    int64
+		reservedMemory  int64
+	}{
+		{map[string]string{}, true, 0, false, 0, 0},
+		{map[string]string{"zone": "zone-1"}, true, 0, false, 0, 0},
+		{map[string]string{OvercommitNodeLabel: "0.2"}, true, 20, true, 0, 0},
+		{map[string]string{OvercommitNodeLabel: "0"}, true, 0, true, 0, 0},
+		{map[string]string{OvercommitNodeLabel: "-0.5"}, true, -50, true, 0, 0},
+		{map[string]string{OvercommitNodeLabel: "-1"}, false, 0, false, 0, 0},
+		{map[string]string{OvercommitNodeLabel: "high"}, false, 0, false, 0, 0},
+		{map[string]string{ReservedCpusNodeLabel: " 2 "}, true, 0, false, 2, 0},
+		{map[string]string{ReservedCpusNodeLabel: "-2"}, false, 0, false, 0, 0},
+		{map[string]string{ReservedCpusNodeLabel: "1.5"}, false, 0, false, 0, 0},
+		{map[string]string{ReservedMemoryNodeLabel: "512"}, true, 0, false, 0, 512},
+		{map[string]string{ReservedMemoryNodeLabel: "4g"}, true, 0, false, 0, 4096},
+		{map[string]string{ReservedMemoryNodeLabel: "512m"}, true, 0, false, 0, 512},
+		{map[string]string{ReservedMemoryNodeLabel: "4x"}, false, 0, false, 0, 0},
+		{map[string]string{OvercommitNodeLabel: "0.1", ReservedCpusNodeLabel: "1", ReservedMemoryNodeLabel: "1g"}, true, 10, true, 1, 1024},
+	}
+
+	for _, test := range tests {
+		resources, err := ParseEngineResources(test.labels)
+		if !test.valid {
+			if err == nil {
+				t.Errorf("ParseEngineResources(%v) is valid, expected error", test.labels)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseEngineResources(%v) error %s, expected valid", test.labels, err)
+			continue
+		}
+		if resources.overcommitRatio != test.overcommitRatio || resources.hasOvercommit != test.hasOvercommit ||
+			resources.reservedCpus != test.reservedCpus || resources.reservedMemory != test.reservedMemory {
+			t.Errorf("ParseEngineResources(%v) is %+v, expected overcommit %d(%t) cpus %d memory %d",
+				test.labels, *resources, test.overcommitRatio, test.hasOvercommit, test.reservedCpus, test.reservedMemory)
+		}
+	}
+}
+
+func TestEngineCapacity(t *testing.T) {
+
+	tests := []struct {
+		capacity        int64
+		reserved        int64
+		overcommitRatio int64
+		total           int64
+	}{
+		{8, 0, 0, 8},
+		{8, 2, 0, 6},
+		{8, 2, 50, 9},
+		{16384, 4096, 5, 12902},
+		{8, 0, -50, 4},
+		{8, 8, 50, 0},
+		{8, 10, 50, 0},
+	}
+
+	for _, test := range tests {
+		if total := engineCapacity(test.capacity, test.reserved, test.overcommitRatio); total != test.total {
+			t.Errorf("engineCapacity(%d, %d, %d) is %d, expected %d", test.capacity, test.reserved, test.overcommitRatio, total, test.total)
+		}
+	}
+}
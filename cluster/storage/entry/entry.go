@@ -2,9 +2,44 @@ package entry
 
 import "github.com/humpback/humpback-center/cluster/types"
 
+import (
+	"time"
+)
+
 //Node is exported
 type Node struct {
 	*types.NodeData
 	NodeLabels   map[string]string `json:"nodelabels"`
 	Availability string            `json:"availability"`
 }
+
+//Quota is exported
+type Quota struct {
+	GroupID string `json:"groupid"`
+	*types.GroupQuota
+}
+
+//MigrationContainer is exported
+//a migrate container outcome, Source is the engine which container is migrated from,
+//NewContainerID and Engine are set after container migrated.
+type MigrationContainer struct {
+	ID             string `json:"id"`
+	State          string `json:"state"`
+	Source         string `json:"source"`
+	Retries        int    `json:"retries"`
+	NewContainerID string `json:"newcontainerid"`
+	Engine         string `json:"engine"`
+	Error          string `json:"error"`
+}
+
+//Migration is exported
+//a meta migrate job, Deadline is the time job starts migrating, Finished is zero until job is finished.
+//Manual job waits approval, it is not started until started by api.
+type Migration struct {
+	MetaID     string                `json:"metaid"`
+	Manual     bool                  `json:"manual"`
+	RetryCount int64                 `json:"retrycount"`
+	Deadline   time.Time             `json:"deadline"`
+	Finished   time.Time             `json:"finished"`
+	Containers []*MigrationContainer `json:"containers"`
+}
@@ -0,0 +1,76 @@
+package migrate
+
+import "github.com/boltdb/bolt"
+import "github.com/humpback/humpback-center/cluster/storage/dao"
+import "github.com/humpback/humpback-center/cluster/storage/entry"
+
+const (
+	// BucketName represents the name of the bucket where this stores data.
+	BucketName = "migrations"
+)
+
+// MigrateStorage is exported
+type MigrateStorage struct {
+	driver *bolt.DB
+}
+
+// NewMigrateStorage is exported
+func NewMigrateStorage(driver *bolt.DB) (*MigrateStorage, error) {
+
+	err := dao.CreateBucket(driver, BucketName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MigrateStorage{
+		driver: driver,
+	}, nil
+}
+
+// MigrationByMetaID is exported
+func (migrateStorage *MigrateStorage) MigrationByMetaID(metaid string) (*entry.Migration, error) {
+
+	var migration entry.Migration
+	err := dao.GetObject(migrateStorage.driver, BucketName, []byte(metaid), &migration)
+	if err != nil {
+		return nil, err
+	}
+	return &migration, nil
+}
+
+// Migrations is exported
+// return all migration entries, include finished migrations.
+func (migrateStorage *MigrateStorage) Migrations() ([]*entry.Migration, error) {
+
+	migrations := []*entry.Migration{}
+	err := migrateStorage.driver.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(BucketName))
+		cursor := bucket.Cursor()
+		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+			var value entry.Migration
+			err := dao.UnmarshalObject(v, &value)
+			if err != nil {
+				return err
+			}
+			migrations = append(migrations, &value)
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+	return migrations, nil
+}
+
+// SetMigration set a meta migration entry.
+func (migrateStorage *MigrateStorage) SetMigration(migration *entry.Migration) error {
+
+	return dao.UpdateObject(migrateStorage.driver, BucketName, []byte(migration.MetaID), migration)
+}
+
+// DeleteMigration deletes a meta migration entry.
+func (migrateStorage *MigrateStorage) DeleteMigration(metaid string) error {
+
+	return dao.DeleteObject(migrateStorage.driver, BucketName, []byte(metaid))
+}
@@ -138,6 +138,26 @@ func (nodeStorage *NodeStorage) SetNodeLabels(ip string, labels map[string]strin
 	})
 }
 
+// SetNodeAvailability set a node availability.
+func (nodeStorage *NodeStorage) SetNodeAvailability(ip string, availability string) error {
+
+	var node *entry.Node
+	node, err := nodeStorage.NodeByIP(ip)
+	if err != nil {
+		return err
+	}
+
+	node.Availability = availability
+	return nodeStorage.driver.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(BucketName))
+		data, err := dao.MarshalObject(node)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(node.IP), data)
+	})
+}
+
 // DeleteNode deletes a node entry.
 func (nodeStorage *NodeStorage) DeleteNode(ip string) error {
 
@@ -0,0 +1,56 @@
+package quota
+
+import "github.com/boltdb/bolt"
+import "github.com/humpback/humpback-center/cluster/types"
+import "github.com/humpback/humpback-center/cluster/storage/dao"
+import "github.com/humpback/humpback-center/cluster/storage/entry"
+
+const (
+	// BucketName represents the name of the bucket where this stores data.
+	BucketName = "quotas"
+)
+
+// QuotaStorage is exported
+type QuotaStorage struct {
+	driver *bolt.DB
+}
+
+// NewQuotaStorage is exported
+func NewQuotaStorage(driver *bolt.DB) (*QuotaStorage, error) {
+
+	err := dao.CreateBucket(driver, BucketName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &QuotaStorage{
+		driver: driver,
+	}, nil
+}
+
+// QuotaByGroupID is exported
+func (quotaStorage *QuotaStorage) QuotaByGroupID(groupid string) (*entry.Quota, error) {
+
+	var quota entry.Quota
+	err := dao.GetObject(quotaStorage.driver, BucketName, []byte(groupid), &quota)
+	if err != nil {
+		return nil, err
+	}
+	return &quota, nil
+}
+
+// SetQuota set a group quota entry.
+func (quotaStorage *QuotaStorage) SetQuota(groupid string, groupQuota *types.GroupQuota) error {
+
+	quota := &entry.Quota{
+		GroupID:    groupid,
+		GroupQuota: groupQuota,
+	}
+	return dao.UpdateObject(quotaStorage.driver, BucketName, []byte(groupid), quota)
+}
+
+// DeleteQuota deletes a group quota entry.
+func (quotaStorage *QuotaStorage) DeleteQuota(groupid string) error {
+
+	return dao.DeleteObject(quotaStorage.driver, BucketName, []byte(groupid))
+}
@@ -2,7 +2,9 @@ package storage
 
 import "github.com/boltdb/bolt"
 import "github.com/humpback/gounits/system"
+import "github.com/humpback/humpback-center/cluster/storage/migrate"
 import "github.com/humpback/humpback-center/cluster/storage/node"
+import "github.com/humpback/humpback-center/cluster/storage/quota"
 
 import (
 	"fmt"
@@ -18,9 +20,11 @@ const (
 // DataStorage defines the implementation of datastore using
 // BoltDB as the storage system.
 type DataStorage struct {
-	path        string
-	driver      *bolt.DB
-	NodeStorage *node.NodeStorage
+	path           string
+	driver         *bolt.DB
+	NodeStorage    *node.NodeStorage
+	QuotaStorage   *quota.QuotaStorage
+	MigrateStorage *migrate.MigrateStorage
 }
 
 // NewDataStorage is exported
@@ -59,7 +63,19 @@ func (storage *DataStorage) Open() error {
 			return err
 		}
 
+		quotaStorage, err := quota.NewQuotaStorage(driver)
+		if err != nil {
+			return err
+		}
+
+		migrateStorage, err := migrate.NewMigrateStorage(driver)
+		if err != nil {
+			return err
+		}
+
 		storage.NodeStorage = nodeStorage
+		storage.QuotaStorage = quotaStorage
+		storage.MigrateStorage = migrateStorage
 		storage.driver = driver
 	}
 	return nil
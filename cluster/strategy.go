@@ -0,0 +1,136 @@
+package cluster
+
+import "github.com/humpback/common/models"
+
+import (
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// SpreadStrategyName is exported
+	SpreadStrategyName = "spread"
+	// BinpackStrategyName is exported
+	BinpackStrategyName = "binpack"
+	// RandomStrategyName is exported
+	RandomStrategyName = "random"
+)
+
+// Strategy is exported
+// scheduling strategy, sorts the weighted engines of a container, best engine first.
+// FilterAllocEngines returns true if engines which already have containers of meta are skipped while other engines have room.
+type Strategy interface {
+	Name() string
+	SortEngines(engines []*WeightedEngine)
+	FilterAllocEngines() bool
+}
+
+// rankEngines returns engines that have enough resources, sorted by strategy.
+// usageWeight is true, engines are also weighted by actual usage.
+func rankEngines(strategy Strategy, engines []*Engine, config models.Container, usageWeight bool, filter *EnginesFilter) []*Engine {
+
+	weightedEngines := selectWeightdEngines(engines, config, usageWeight, filter)
+	strategy.SortEngines(weightedEngines)
+	return weightedEngines.Engines()
+}
+
+// NewStrategy is exported
+// Return a built-in strategy of name, empty name is spread strategy.
+func NewStrategy(name string) (Strategy, error) {
+
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", SpreadStrategyName:
+		return &SpreadStrategy{}, nil
+	case BinpackStrategyName:
+		return &BinpackStrategy{}, nil
+	case RandomStrategyName:
+		return NewRandomStrategy(), nil
+	}
+	return nil, ErrClusterStrategyInvalid
+}
+
+// SpreadStrategy is exported
+// prefers the least loaded engines, ties are broken by engine containers count.
+type SpreadStrategy struct{}
+
+// Name is exported
+func (strategy *SpreadStrategy) Name() string {
+
+	return SpreadStrategyName
+}
+
+// SortEngines is exported
+func (strategy *SpreadStrategy) SortEngines(engines []*WeightedEngine) {
+
+	sort.Sort(weightedEngines(engines))
+}
+
+// FilterAllocEngines is exported
+func (strategy *SpreadStrategy) FilterAllocEngines() bool {
+
+	return true
+}
+
+// BinpackStrategy is exported
+// prefers the most loaded engines which still have room, keeps other engines free.
+type BinpackStrategy struct{}
+
+// Name is exported
+func (strategy *BinpackStrategy) Name() string {
+
+	return BinpackStrategyName
+}
+
+// SortEngines is exported
+func (strategy *BinpackStrategy) SortEngines(engines []*WeightedEngine) {
+
+	sort.Sort(sort.Reverse(weightedEngines(engines)))
+}
+
+// FilterAllocEngines is exported
+// containers of meta are packed, an engine which has meta containers is filled first.
+func (strategy *BinpackStrategy) FilterAllocEngines() bool {
+
+	return false
+}
+
+// RandomStrategy is exported
+// randomly selects an engine which has room, ignores engines load.
+type RandomStrategy struct {
+	sync.Mutex
+	randSeed *rand.Rand
+}
+
+// NewRandomStrategy is exported
+func NewRandomStrategy() *RandomStrategy {
+
+	return &RandomStrategy{
+		randSeed: rand.New(rand.NewSource(time.Now().UTC().UnixNano())),
+	}
+}
+
+// Name is exported
+func (strategy *RandomStrategy) Name() string {
+
+	return RandomStrategyName
+}
+
+// SortEngines is exported
+func (strategy *RandomStrategy) SortEngines(engines []*WeightedEngine) {
+
+	strategy.Lock()
+	for i := len(engines) - 1; i > 0; i-- {
+		j := strategy.randSeed.Intn(i + 1)
+		engines[i], engines[j] = engines[j], engines[i]
+	}
+	strategy.Unlock()
+}
+
+// FilterAllocEngines is exported
+func (strategy *RandomStrategy) FilterAllocEngines() bool {
+
+	return true
+}
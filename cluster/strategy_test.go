@@ -0,0 +1,74 @@
+package cluster
+
+import "github.com/humpback/common/models"
+
+import (
+	"testing"
+)
+
+func TestRankEngines(t *testing.T) {
+
+	engine1 := newTestEngine("192.168.2.1", 8, 8192, nil)
+	engine2 := newTestEngine("192.168.2.2", 8, 8192, nil)
+	engine3 := newTestEngine("192.168.2.3", 8, 8192, nil)
+	engine4 := newTestEngine("192.168.2.4", 2, 8192, nil)
+	engine1.reservePending("meta-other", models.Container{Name: "other-1", CPUShares: 4, Memory: 4096})
+	engine3.reservePending("meta-other", models.Container{Name: "other-2", CPUShares: 2, Memory: 2048})
+	engine4.reservePending("meta-other", models.Container{Name: "other-3", CPUShares: 2})
+	engines := []*Engine{engine1, engine2, engine3, engine4}
+	config := models.Container{Name: "web", CPUShares: 1, Memory: 1024}
+
+	tests := []struct {
+		strategy string
+		ips      []string
+	}{
+		{SpreadStrategyName, []string{"192.168.2.2", "192.168.2.3", "192.168.2.1"}},
+		{BinpackStrategyName, []string{"192.168.2.1", "192.168.2.3", "192.168.2.2"}},
+	}
+
+	for _, test := range tests {
+		strategy, err := NewStrategy(test.strategy)
+		if err != nil {
+			t.Errorf("NewStrategy(%q) error %s", test.strategy, err)
+			continue
+		}
+		//engine4 has no room for config, it is rejected.
+		filter := NewEnginesFilter()
+		ips := []string{}
+		for _, engine := range rankEngines(strategy, engines, config, false, filter) {
+			ips = append(ips, engine.IP)
+		}
+		if !equalStrings(ips, test.ips) {
+			t.Errorf("rankEngines(%s) is %v, expected %v", test.strategy, ips, test.ips)
+		}
+		if _, ret := filter.Rejects()[engine4.IP]; !ret {
+			t.Errorf("rankEngines(%s) doesn't reject %s", test.strategy, engine4.IP)
+		}
+	}
+
+	random, _ := NewStrategy(RandomStrategyName)
+	if ranked := rankEngines(random, engines, config, false, nil); len(ranked) != 3 {
+		t.Errorf("rankEngines(%s) returns %d engines, expected 3", RandomStrategyName, len(ranked))
+	}
+
+	if _, err := NewStrategy("roundrobin"); err != ErrClusterStrategyInvalid {
+		t.Errorf("NewStrategy(%q) error is %v, expected %s", "roundrobin", err, ErrClusterStrategyInvalid)
+	}
+}
+
+func TestSelectCreateEngineBinpack(t *testing.T) {
+
+	engine1 := newTestEngine("192.168.2.1", 8, 8192, nil)
+	engine2 := newTestEngine("192.168.2.2", 8, 8192, nil)
+	engine3 := newTestEngine("192.168.2.3", 8, 8192, nil)
+	engine2.reservePending("meta-other", models.Container{Name: "other-1", CPUShares: 4, Memory: 4096})
+	cluster := newTestCluster(t, engine1, engine2, engine3)
+
+	metaData := &MetaData{MetaBase: MetaBase{GroupID: testGroupID, MetaID: "meta-web", Config: models.Container{Name: "web", CPUShares: 1, Memory: 1024}}}
+	metaData.Placement.Strategy = BinpackStrategyName
+	ips := scheduleTestContainers(t, cluster, metaData, 3)
+	expected := []string{"192.168.2.2", "192.168.2.2", "192.168.2.2"}
+	if !equalStrings(ips, expected) {
+		t.Errorf("binpack instances are on %v, expected %v", ips, expected)
+	}
+}
@@ -0,0 +1,13 @@
+package types
+
+// ContainerEvent is exported
+// a docker container event of agent, fields are same as docker events message.
+// Action is event action, such as create, start, die, destroy or health_status: healthy.
+type ContainerEvent struct {
+	Type     string `json:"Type"`
+	Action   string `json:"Action"`
+	ID       string `json:"id"`
+	Status   string `json:"status"`
+	Time     int64  `json:"time"`
+	TimeNano int64  `json:"timeNano"`
+}
@@ -3,10 +3,12 @@ package types
 import "github.com/humpback/common/models"
 
 // EngineContainer is exported
+// DynamicPorts: host ports allocated by center, placement DynamicPorts enabled.
 type EngineContainer struct {
-	IP        string           `json:"IP"`
-	HostName  string           `json:"HostName"`
-	Container models.Container `json:"Container"`
+	IP           string               `json:"IP"`
+	HostName     string               `json:"HostName"`
+	Container    models.Container     `json:"Container"`
+	DynamicPorts []models.PortBinding `json:"DynamicPorts"`
 }
 
 // GroupContainer is exported
@@ -15,6 +17,8 @@ type GroupContainer struct {
 	MetaID        string             `json:"MetaId"`
 	IsRemoveDelay bool               `json:"IsRemoveDelay"`
 	IsRecovery    bool               `json:"IsRecovery"`
+	Priority      int                `json:"Priority"`
+	MigratePolicy MigratePolicy      `json:"MigratePolicy"`
 	Instances     int                `json:"Instances"`
 	Placement     Placement          `json:"Placement"`
 	WebHooks      WebHooks           `json:"WebHooks"`
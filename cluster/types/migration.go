@@ -0,0 +1,40 @@
+package types
+
+import "time"
+
+// MigrateContainer is exported
+// a migrate container state, Source is the engine which container is migrated from,
+// Target and NewContainerID are set after container migrated, Retries is the failed times of container.
+type MigrateContainer struct {
+	ID             string `json:"Id"`
+	State          string `json:"State"`
+	Source         string `json:"Source"`
+	Target         string `json:"Target"`
+	NewContainerID string `json:"NewContainerId"`
+	Retries        int    `json:"Retries"`
+	Error          string `json:"Error"`
+}
+
+// Migration is exported
+// an active meta migration, Deadline is the time migration starts migrating containers,
+// RetryCount is the remaining retry rounds of failed containers.
+// Manual migration is pending approval, it is not started until started by api, Deadline is zero.
+type Migration struct {
+	MetaID     string              `json:"MetaId"`
+	GroupID    string              `json:"GroupId"`
+	Name       string              `json:"Name"`
+	Manual     bool                `json:"Manual"`
+	RetryCount int64               `json:"RetryCount"`
+	Deadline   time.Time           `json:"Deadline"`
+	Containers []*MigrateContainer `json:"Containers"`
+}
+
+// MigratePolicy is exported
+// meta containers migration policy when their engine is offline, unhealthy or draining.
+// Mode: 'auto' migrates containers after delay, 'manual' migrates containers after migration is started by api,
+// 'never' never migrates containers, empty is 'auto'.
+// Delay: offline engine migrate delay of meta, like '60s', empty is cluster 'migratedelay'.
+type MigratePolicy struct {
+	Mode  string `json:"Mode"`
+	Delay string `json:"Delay"`
+}
@@ -0,0 +1,20 @@
+package types
+
+import "time"
+
+// Operation is exported
+// a meta job snapshot, jobs of a meta are executed one by one in submitted order.
+// State is one of queued, running, completed or failed, Result is set when job is completed.
+type Operation struct {
+	ID       string      `json:"Id"`
+	GroupID  string      `json:"GroupId"`
+	MetaID   string      `json:"MetaId"`
+	Name     string      `json:"Name"`
+	Kind     string      `json:"Kind"`
+	State    string      `json:"State"`
+	Error    string      `json:"Error"`
+	Result   interface{} `json:"Result"`
+	Created  time.Time   `json:"Created"`
+	Started  time.Time   `json:"Started"`
+	Finished time.Time   `json:"Finished"`
+}
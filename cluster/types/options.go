@@ -6,15 +6,21 @@ package types
 //`ForceRemove` is an attached property. When `IsReCreate` is true, it means to force delete or directly upgrade an existing containers.
 //`IsRemoveDelay` delay (8 minutes) remove unused containers for service debounce.
 //`IsRecovery` service containers recovery check enable.
+//`Priority` service priority, containers of strictly lower priority metas in the same group can be evicted when no engine has room, default is 0.
+//`MigratePolicy` service containers migration policy, 'auto', 'manual' or 'never' and migrate delay, default is 'auto' after cluster migrate delay.
 type CreateOption struct {
-	IsReCreate    bool `json:"IsReCreate"`
-	ForceRemove   bool `json:"ForceRemove"`
-	IsRemoveDelay bool `json:"IsRemoveDelay"`
-	IsRecovery    bool `json:"IsRecovery"`
+	IsReCreate    bool          `json:"IsReCreate"`
+	ForceRemove   bool          `json:"ForceRemove"`
+	IsRemoveDelay bool          `json:"IsRemoveDelay"`
+	IsRecovery    bool          `json:"IsRecovery"`
+	Priority      int           `json:"Priority"`
+	MigratePolicy MigratePolicy `json:"MigratePolicy"`
 }
 
 //UpdateOption is exported
 type UpdateOption struct {
-	IsRemoveDelay bool `json:"IsRemoveDelay"`
-	IsRecovery    bool `json:"IsRecovery"`
+	IsRemoveDelay bool          `json:"IsRemoveDelay"`
+	IsRecovery    bool          `json:"IsRecovery"`
+	Priority      int           `json:"Priority"`
+	MigratePolicy MigratePolicy `json:"MigratePolicy"`
 }
@@ -0,0 +1,14 @@
+package types
+
+// EnginePerformance is exported
+// a performance sample of engine host collected from agent, CPUPercent is host cpu usage percent of all cpus,
+// MemoryUsage is host used memory bytes, Load1, Load5 and Load15 are host load averages.
+// Timestamp is the unix time which sample is collected by cluster.
+type EnginePerformance struct {
+	Timestamp   int64   `json:"Timestamp"`
+	CPUPercent  float64 `json:"CPUPercent"`
+	MemoryUsage int64   `json:"MemoryUsage"`
+	Load1       float64 `json:"Load1"`
+	Load5       float64 `json:"Load5"`
+	Load15      float64 `json:"Load15"`
+}
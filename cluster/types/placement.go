@@ -16,10 +16,33 @@ type Platform struct {
 	OS           string `json:"OS"`
 }
 
+// Affinity is exported
+// Type: 'affinity' co-locate with other metas, 'antiaffinity' keep away from other metas.
+// Mode: 'hard' filters engines, 'soft' only prefers engines, default is 'hard'.
+// MetaName or MetaLabel ('key' or 'key=value') selects other metas in the same group.
+type Affinity struct {
+	Type      string `json:"Type"`
+	Mode      string `json:"Mode"`
+	MetaName  string `json:"MetaName"`
+	MetaLabel string `json:"MetaLabel"`
+}
+
 // Placement is exported
 // Cluster services placement constraints
+// Strategy: scheduling strategy 'spread', 'binpack' or 'random', empty is cluster default strategy.
+// MaxPerEngine: max containers of the meta on one engine, 0 is unlimited.
+// DynamicPorts: center assigns a free host port to each port binding without PublicPort, bridge or nat network only.
+// PortRange: dynamic host ports range, like '30000-30999', empty is cluster default port range.
+// ReducePolicy: containers reduced first when scaling down, 'index', 'newest', 'oldest', 'unhealthy' or 'spread',
+// empty reduces containers of the engine which has most instances first, crash-looping containers are always reduced first.
 type Placement struct {
-	Constraints []string     `json:"Constraints"`
-	Preferences []Preference `json:"Preferences"`
-	Platforms   []Platform   `json:"Platforms"`
+	Constraints  []string     `json:"Constraints"`
+	Preferences  []Preference `json:"Preferences"`
+	Platforms    []Platform   `json:"Platforms"`
+	Affinities   []Affinity   `json:"Affinities"`
+	Strategy     string       `json:"Strategy"`
+	MaxPerEngine int          `json:"MaxPerEngine"`
+	DynamicPorts bool         `json:"DynamicPorts"`
+	PortRange    string       `json:"PortRange"`
+	ReducePolicy string       `json:"ReducePolicy"`
 }
@@ -0,0 +1,34 @@
+package types
+
+// PlanRejected is exported
+// engine rejected reason of a plan instance.
+type PlanRejected struct {
+	IP       string `json:"IP"`
+	HostName string `json:"HostName"`
+	Reason   string `json:"Reason"`
+}
+
+// PlanInstance is exported
+// IP and HostName is the engine which instance would land on, Error is set when no engine is available.
+// Evicted is the lower priority containers which would be evicted if instance preempts the engine.
+type PlanInstance struct {
+	Index    int            `json:"Index"`
+	IP       string         `json:"IP"`
+	HostName string         `json:"HostName"`
+	Error    string         `json:"Error"`
+	Evicted  []string       `json:"Evicted"`
+	Rejected []PlanRejected `json:"Rejected"`
+}
+
+// PlanContainers is exported
+// Action: 'create', 're-create', 'append', 'reduce' or 'none', the way of request would be executed.
+// Error is set when request would be rejected before scheduling, such as group quota exceeded.
+type PlanContainers struct {
+	GroupID   string          `json:"GroupId"`
+	MetaID    string          `json:"MetaId"`
+	Action    string          `json:"Action"`
+	Instances int             `json:"Instances"`
+	Strategy  string          `json:"Strategy"`
+	Error     string          `json:"Error"`
+	Plans     []*PlanInstance `json:"Plans"`
+}
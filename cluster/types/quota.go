@@ -0,0 +1,26 @@
+package types
+
+// GroupQuota is exported
+// group resources quota, 0 is unlimited.
+// MaxCPUShares is the sum of containers CPUShares, MaxMemory is the sum of containers memory(MB).
+type GroupQuota struct {
+	MaxInstances int   `json:"MaxInstances"`
+	MaxCPUShares int64 `json:"MaxCPUShares"`
+	MaxMemory    int64 `json:"MaxMemory"`
+}
+
+// GroupResources is exported
+// group containers consumption, Memory is MB.
+type GroupResources struct {
+	Instances int   `json:"Instances"`
+	CPUShares int64 `json:"CPUShares"`
+	Memory    int64 `json:"Memory"`
+}
+
+// GroupQuotaUsage is exported
+// Quota is nil if group has no quota.
+type GroupQuotaUsage struct {
+	GroupID string         `json:"GroupId"`
+	Quota   *GroupQuota    `json:"Quota"`
+	Usage   GroupResources `json:"Usage"`
+}
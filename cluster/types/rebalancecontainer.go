@@ -0,0 +1,21 @@
+package types
+
+// RebalanceMove is exported
+// move a container from engine to an other engine, new container is created before original container is removed.
+type RebalanceMove struct {
+	ContainerID string `json:"ContainerId"`
+	Name        string `json:"Name"`
+	From        string `json:"From"`
+	To          string `json:"To"`
+}
+
+// RebalanceContainers is exported
+// Moves are executed one by one, Pause is the interval between two moves.
+// Moves are computed again when rebalance operation runs, operation result is the executed moves.
+type RebalanceContainers struct {
+	GroupID     string           `json:"GroupId"`
+	MetaID      string           `json:"MetaId"`
+	OperationID string           `json:"OperationId,omitempty"`
+	Pause       string           `json:"Pause"`
+	Moves       []*RebalanceMove `json:"Moves"`
+}
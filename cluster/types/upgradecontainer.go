@@ -7,6 +7,20 @@ type UpgradeContainerResponse struct {
 	ID string `json:"Id"`
 }
 
+// RollingUpgrade is exported
+// rolling upgrade meta containers batch by batch.
+// BatchSize: containers upgraded in a batch, default is 1.
+// MaxUnavailable: max containers of a batch upgraded in place at the same time, original container is removed before new container created.
+// MaxSurge: max containers of a batch upgraded by creating new container first, then original container is removed,
+// ignored when meta binds host ports. MaxUnavailable and MaxSurge are both 0, MaxUnavailable is 1.
+// Delay: interval between two batches, like '30s', empty is cluster upgrade delay.
+type RollingUpgrade struct {
+	BatchSize      int    `json:"BatchSize"`
+	MaxUnavailable int    `json:"MaxUnavailable"`
+	MaxSurge       int    `json:"MaxSurge"`
+	Delay          string `json:"Delay"`
+}
+
 // UpgradeContainer is exported
 type UpgradeContainer struct {
 	IP       string `json:"IP"`
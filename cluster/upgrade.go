@@ -1,10 +1,12 @@
 package cluster
 
+import "github.com/humpback/humpback-center/cluster/types"
 import "github.com/humpback/gounits/logger"
 import "github.com/humpback/common/models"
 
 import (
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 )
@@ -25,6 +27,26 @@ const (
 	UpgradeRecovery
 )
 
+// ValidateRollingUpgrade is exported
+// rolling is nil, upgrade is not a rolling upgrade.
+func ValidateRollingUpgrade(rolling *types.RollingUpgrade) error {
+
+	if rolling == nil {
+		return nil
+	}
+
+	if rolling.BatchSize < 0 || rolling.MaxUnavailable < 0 || rolling.MaxSurge < 0 {
+		return ErrClusterRollingUpgradeInvalid
+	}
+
+	if delay := strings.TrimSpace(rolling.Delay); delay != "" {
+		if dur, err := time.ParseDuration(delay); err != nil || dur < 0 {
+			return ErrClusterRollingUpgradeInvalid
+		}
+	}
+	return nil
+}
+
 // UpgradeContainer is exported
 type UpgradeContainer struct {
 	Original *Container
@@ -54,11 +76,59 @@ func (upgradeContainer *UpgradeContainer) Execute(newImageTag string) error {
 	return nil
 }
 
+// Surge is exported
+// upgrade originalContainer by creating a new tag container on a scheduled engine first, then remove originalContainer.
+// originalContainer isn't counted by scheduling of new container, it is replaced.
+func (upgradeContainer *UpgradeContainer) Surge(cluster *Cluster, metaData *MetaData, config models.Container) error {
+
+	originalContainer := upgradeContainer.Original
+	originalContainer.Engine.reserveReplacing(originalContainer.Config.ID)
+	createdContainers, err := cluster.createContainers(metaData, 1, nil, config)
+	originalContainer.Engine.releaseReplacing(originalContainer.Config.ID)
+	if len(createdContainers) == 0 {
+		upgradeContainer.State = UpgradeFailure
+		if err == nil {
+			err = ErrClusterCreateContainerFailure
+		}
+		return err
+	}
+
+	created := createdContainers[0]
+	engine := cluster.GetEngine(created.IP)
+	if engine == nil {
+		upgradeContainer.State = UpgradeFailure
+		return fmt.Errorf("engine %s not found", created.IP)
+	}
+
+	newContainer := engine.Container(created.ID)
+	if newContainer == nil {
+		upgradeContainer.State = UpgradeFailure
+		return fmt.Errorf("engine %s container %s not found", engine.IP, ShortContainerID(created.ID))
+	}
+	upgradeContainer.New = newContainer
+	upgradeContainer.State = UpgradeCompleted
+
+	//original container is still running if remove failure, removing is retried by engine remove pool.
+	if err := originalContainer.Engine.RemoveContainer(originalContainer.Config.ID); err != nil {
+		cluster.configCache.RemoveContainerBaseConfig(metaData.MetaID, originalContainer.Config.ID)
+		originalContainer.Engine.delayRemoveContainer(metaData.MetaID, originalContainer.Config.ID)
+		logger.WARN("[#cluster#] engine %s upgrading, remove original container %s failure, retry later.", originalContainer.Engine.IP, ShortContainerID(originalContainer.Config.ID))
+	}
+	return nil
+}
+
+// isSurgePlacementError returns true if surge failure is no engine has room for a new container.
+func isSurgePlacementError(err error) bool {
+
+	return err == ErrClusterNoEngineAvailable || err == ErrClusterNoPlatformEngineAvailable ||
+		err == ErrClusterMaxPerEngineExceeded || err == ErrClusterHostPortsConflict
+}
+
 // Recovery is exported
-// upgrade container failure, recovery completed containers to original image tag
+// upgrade container failure, recovery completed containers to original image tag on new container engine.
 func (upgradeContainer *UpgradeContainer) Recovery(originalImageTag string) error {
 
-	engine := upgradeContainer.Original.Engine
+	engine := upgradeContainer.New.Engine
 	if !engine.IsHealthy() {
 		return nil
 	}
@@ -76,20 +146,28 @@ func (upgradeContainer *UpgradeContainer) Recovery(originalImageTag string) erro
 }
 
 // Upgrader is exported
+// upgrade meta containers batch by batch, batchSize containers are upgraded in a batch,
+// maxSurge containers of a batch are surged, others are upgraded in place and maxUnavailable of them at the same time.
+// delayInterval is the interval between two batches. completed containers are recovered when a container upgraded failure.
 type Upgrader struct {
 	sync.RWMutex
-	MetaID        string
-	OriginalTag   string
-	NewTag        string
-	configCache   *ContainersConfigCache
-	delayInterval time.Duration
-	callback      UpgraderHandleFunc
-	containers    []*UpgradeContainer
+	MetaID         string
+	OriginalTag    string
+	NewTag         string
+	cluster        *Cluster
+	configCache    *ContainersConfigCache
+	batchSize      int
+	maxUnavailable int
+	maxSurge       int
+	delayInterval  time.Duration
+	callback       UpgraderHandleFunc
+	containers     []*UpgradeContainer
+	errMsgs        []string
 }
 
 // NewUpgrader is exported
-func NewUpgrader(metaid string, originalTag string, newTag string, containers Containers, upgradeDelay time.Duration,
-	configCache *ContainersConfigCache, callback UpgraderHandleFunc) *Upgrader {
+func NewUpgrader(metaid string, originalTag string, newTag string, containers Containers, rolling types.RollingUpgrade, upgradeDelay time.Duration,
+	cluster *Cluster, callback UpgraderHandleFunc) *Upgrader {
 
 	upgradeContainers := []*UpgradeContainer{}
 	for _, container := range containers {
@@ -102,14 +180,27 @@ func NewUpgrader(metaid string, originalTag string, newTag string, containers Co
 		}
 	}
 
+	if rolling.BatchSize <= 0 {
+		rolling.BatchSize = 1
+	}
+
+	if rolling.MaxUnavailable <= 0 && rolling.MaxSurge <= 0 {
+		rolling.MaxUnavailable = 1
+	}
+
 	return &Upgrader{
-		MetaID:        metaid,
-		OriginalTag:   originalTag,
-		NewTag:        newTag,
-		configCache:   configCache,
-		delayInterval: upgradeDelay,
-		callback:      callback,
-		containers:    upgradeContainers,
+		MetaID:         metaid,
+		OriginalTag:    originalTag,
+		NewTag:         newTag,
+		cluster:        cluster,
+		configCache:    cluster.configCache,
+		batchSize:      rolling.BatchSize,
+		maxUnavailable: rolling.MaxUnavailable,
+		maxSurge:       rolling.MaxSurge,
+		delayInterval:  upgradeDelay,
+		callback:       callback,
+		containers:     upgradeContainers,
+		errMsgs:        []string{},
 	}
 }
 
@@ -117,21 +208,34 @@ func NewUpgrader(metaid string, originalTag string, newTag string, containers Co
 func (upgrader *Upgrader) Start(upgradeCh chan<- bool) {
 
 	var (
-		err     error
-		ret     bool
-		errMsgs []string
+		err error
+		ret bool
 	)
 
 	ret = true
-	errMsgs = []string{}
 	upgrader.Lock()
 	defer upgrader.Unlock()
 	upgrader.configCache.SetImageTag(upgrader.MetaID, upgrader.NewTag)
-	for _, upgradeContainer := range upgrader.containers {
-		if err = upgradeContainer.Execute(upgrader.NewTag); err != nil {
-			upgrader.configCache.RemoveContainerBaseConfig(upgrader.MetaID, upgradeContainer.Original.Config.ID)
-			errMsgs = append(errMsgs, "upgrade container execute, "+err.Error())
-			logger.ERROR("[#cluster#] upgrade container %s execute %s", ShortContainerID(upgradeContainer.Original.Config.ID), err.Error())
+	for i := 0; i < len(upgrader.containers); {
+		if i > 0 {
+			upgrader.Unlock()
+			time.Sleep(upgrader.delayInterval)
+			upgrader.Lock()
+		}
+
+		count := upgrader.batchSize
+		if upgrader.maxUnavailable <= 0 && count > upgrader.maxSurge {
+			count = upgrader.maxSurge
+		}
+
+		if i+count > len(upgrader.containers) {
+			count = len(upgrader.containers) - i
+		}
+
+		batch := upgrader.containers[i : i+count]
+		i = i + count
+		logger.INFO("[#cluster#] upgrade %s > %s batch, %d containers.", upgrader.MetaID, upgrader.NewTag, len(batch))
+		if err = upgrader.executeBatch(batch); err != nil {
 			break
 		}
 	}
@@ -143,20 +247,111 @@ func (upgrader *Upgrader) Start(upgradeCh chan<- bool) {
 			if upgradeContainer.State == UpgradeCompleted {
 				if err := upgradeContainer.Recovery(upgrader.OriginalTag); err != nil {
 					upgrader.configCache.RemoveContainerBaseConfig(upgrader.MetaID, upgradeContainer.New.Config.ID)
-					errMsgs = append(errMsgs, "upgrade container recovery, "+err.Error())
+					upgrader.errMsgs = append(upgrader.errMsgs, "upgrade container recovery, "+err.Error())
 					logger.ERROR("[#cluster#] upgrade container %s recovery %s", ShortContainerID(upgradeContainer.New.Config.ID), err.Error())
 				}
 			}
 		}
 	}
-	upgrader.callback(upgrader, errMsgs)
+	upgrader.callback(upgrader, upgrader.errMsgs)
 	upgradeCh <- ret
 }
 
+// executeBatch upgrades a batch of containers, surged containers are upgraded one by one first,
+// then others are upgraded in place, at most maxUnavailable at the same time.
+// return the first upgrade error of batch.
+func (upgrader *Upgrader) executeBatch(batch []*UpgradeContainer) error {
+
+	surge := upgrader.maxSurge
+	if surge > len(batch) {
+		surge = len(batch)
+	}
+
+	var err error
+	if surge > 0 {
+		metaData := upgrader.configCache.GetMetaData(upgrader.MetaID)
+		if metaData == nil {
+			err = ErrClusterMetaDataNotFound
+			upgrader.errMsgs = append(upgrader.errMsgs, "upgrade container surge, "+err.Error())
+			return err
+		}
+		for _, upgradeContainer := range batch[:surge] {
+			err = upgradeContainer.Surge(upgrader.cluster, metaData, metaData.Config)
+			if isSurgePlacementError(err) {
+				//no engine has room for a surged container, upgrade original container in place.
+				logger.WARN("[#cluster#] upgrade container %s surge %s, upgrade in place.", ShortContainerID(upgradeContainer.Original.Config.ID), err.Error())
+				if err = upgradeContainer.Execute(upgrader.NewTag); err != nil {
+					upgrader.configCache.RemoveContainerBaseConfig(upgrader.MetaID, upgradeContainer.Original.Config.ID)
+				}
+			}
+			if err != nil {
+				upgrader.errMsgs = append(upgrader.errMsgs, "upgrade container surge, "+err.Error())
+				logger.ERROR("[#cluster#] upgrade container %s surge %s", ShortContainerID(upgradeContainer.Original.Config.ID), err.Error())
+				return err
+			}
+		}
+	}
+
+	mutex := sync.Mutex{}
+	wgroup := sync.WaitGroup{}
+	unavailableCh := make(chan struct{}, upgrader.maxUnavailable)
+	for _, upgradeContainer := range batch[surge:] {
+		wgroup.Add(1)
+		unavailableCh <- struct{}{}
+		go func(upgradeContainer *UpgradeContainer) {
+			defer func() {
+				<-unavailableCh
+				wgroup.Done()
+			}()
+			if e := upgradeContainer.Execute(upgrader.NewTag); e != nil {
+				upgrader.configCache.RemoveContainerBaseConfig(upgrader.MetaID, upgradeContainer.Original.Config.ID)
+				logger.ERROR("[#cluster#] upgrade container %s execute %s", ShortContainerID(upgradeContainer.Original.Config.ID), e.Error())
+				mutex.Lock()
+				upgrader.errMsgs = append(upgrader.errMsgs, "upgrade container execute, "+e.Error())
+				if err == nil {
+					err = e
+				}
+				mutex.Unlock()
+			}
+		}(upgradeContainer)
+	}
+	wgroup.Wait()
+	return err
+}
+
+// UpgradeContainers is exported
+// return new containers of completed upgrade containers.
+func (upgrader *Upgrader) UpgradeContainers() *types.UpgradeContainers {
+
+	upgrader.RLock()
+	defer upgrader.RUnlock()
+	upgradeContainers := types.UpgradeContainers{}
+	for _, upgradeContainer := range upgrader.containers {
+		if upgradeContainer.State == UpgradeCompleted {
+			engine := upgradeContainer.New.Engine
+			upgradeContainers = upgradeContainers.SetUpgradePair(engine.IP, engine.Name, upgradeContainer.New.Config.Container)
+		}
+	}
+	return &upgradeContainers
+}
+
+// Error is exported
+// return upgrade error of upgrader, nil if upgrader is succeed.
+func (upgrader *Upgrader) Error() error {
+
+	upgrader.RLock()
+	defer upgrader.RUnlock()
+	if len(upgrader.errMsgs) > 0 {
+		return fmt.Errorf("%s", strings.Join(upgrader.errMsgs, "; "))
+	}
+	return nil
+}
+
 // UpgraderHandleFunc exported
 type UpgraderHandleFunc func(upgrader *Upgrader, errMsgs []string)
 
 // UpgradeContainersCache is exported
+// delayInterval is the default interval between two batches.
 type UpgradeContainersCache struct {
 	sync.RWMutex
 	Cluster       *Cluster
@@ -180,28 +375,37 @@ func (cache *UpgradeContainersCache) SetCluster(cluster *Cluster) {
 }
 
 // Upgrade is exported
-func (cache *UpgradeContainersCache) Upgrade(upgradeCh chan<- bool, metaid string, newTag string, containers Containers) {
+// start rolling upgrade containers of meta, upgradeCh receives upgrade result after upgrader is done.
+// return nil if meta not found or meta is upgrading.
+func (cache *UpgradeContainersCache) Upgrade(upgradeCh chan<- bool, metaid string, newTag string, containers Containers, rolling types.RollingUpgrade) *Upgrader {
 
 	if cache.Cluster == nil || cache.Cluster.configCache == nil {
-		return
+		return nil
 	}
 
 	configCache := cache.Cluster.configCache
 	metaData := configCache.GetMetaData(metaid)
 	if metaData == nil {
-		return
+		return nil
+	}
+
+	upgradeDelay := cache.delayInterval
+	if dur, err := time.ParseDuration(strings.TrimSpace(rolling.Delay)); err == nil && dur >= 0 {
+		upgradeDelay = dur
 	}
 
 	cache.Lock()
-	if _, ret := cache.upgraders[metaid]; !ret {
-		upgrader := NewUpgrader(metaData.MetaID, metaData.ImageTag, newTag, containers, cache.delayInterval, configCache, cache.UpgraderHandleFunc)
-		if upgrader != nil {
-			cache.upgraders[metaData.MetaID] = upgrader
-			logger.INFO("[#cluster#] upgrade start %s > %s", upgrader.MetaID, upgrader.NewTag)
-			go upgrader.Start(upgradeCh)
-		}
+	defer cache.Unlock()
+	if _, ret := cache.upgraders[metaid]; ret {
+		return nil
 	}
-	cache.Unlock()
+
+	upgrader := NewUpgrader(metaData.MetaID, metaData.ImageTag, newTag, containers, rolling, upgradeDelay, cache.Cluster, cache.UpgraderHandleFunc)
+	cache.upgraders[metaData.MetaID] = upgrader
+	logger.INFO("[#cluster#] upgrade start %s > %s, batch size %d, max unavailable %d, max surge %d, delay %s",
+		upgrader.MetaID, upgrader.NewTag, upgrader.batchSize, upgrader.maxUnavailable, upgrader.maxSurge, upgrader.delayInterval)
+	go upgrader.Start(upgradeCh)
+	return upgrader
 }
 
 // Contains is exported
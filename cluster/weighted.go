@@ -3,6 +3,10 @@ package cluster
 import "github.com/humpback/gounits/logger"
 import "github.com/humpback/common/models"
 
+import (
+	"fmt"
+)
+
 // WeightedEngine is exported
 type WeightedEngine struct {
 	engine *Engine
@@ -60,35 +64,74 @@ func (engines weightedEngines) Engines() []*Engine {
 	return out
 }
 
-func selectWeightdEngines(engines []*Engine, config models.Container) weightedEngines {
+// engineWeight returns engine weight of a container config.
+// usedMemory is MB, error describes why engine has no room for config.
+func engineWeight(totalCpus int64, totalMemory int64, usedCpus int64, usedMemory int64, config models.Container) (int64, error) {
 
-	out := weightedEngines{}
-	for _, engine := range engines {
-		totalCpus := engine.TotalCpus()
-		totalMemory := engine.TotalMemory()
-		if totalMemory < config.Memory || totalCpus < config.CPUShares {
-			logger.INFO("[#cluster#] weighted engine %s filter.", engine.IP)
-			continue
-		}
+	if totalMemory < config.Memory {
+		return 0, fmt.Errorf("insufficient memory, total %dMB less than %dMB", totalMemory, config.Memory)
+	}
 
-		var cpuScore int64 = 100
-		var memoryScore int64 = 100
+	if totalCpus < config.CPUShares {
+		return 0, fmt.Errorf("insufficient cpus, total %d less than %d", totalCpus, config.CPUShares)
+	}
 
-		if config.CPUShares > 0 {
-			cpuScore = (engine.UsedCpus() + config.CPUShares) * 100 / totalCpus
-		}
+	var cpuScore int64 = 100
+	var memoryScore int64 = 100
+
+	if config.CPUShares > 0 {
+		cpuScore = (usedCpus + config.CPUShares) * 100 / totalCpus
+	}
+
+	if config.Memory > 0 {
+		memoryScore = (usedMemory + config.Memory) * 100 / totalMemory
+	}
+
+	if cpuScore > 100 {
+		return 0, fmt.Errorf("insufficient cpus, used %d + %d exceeds total %d", usedCpus, config.CPUShares, totalCpus)
+	}
+
+	if memoryScore > 100 {
+		return 0, fmt.Errorf("insufficient memory, used %dMB + %dMB exceeds total %dMB", usedMemory, config.Memory, totalMemory)
+	}
+	return cpuScore + memoryScore, nil
+}
 
-		if config.Memory > 0 {
-			memoryScore = (engine.UsedMemory()/1024/1024 + config.Memory) * 100 / totalMemory
+// engineUsed returns engine used cpus and used memory(MB) for scheduling, the sum of containers requested resources.
+// usageWeight is true, engine host actual usage is used when it is higher, so engines hot with unmanaged containers are avoided.
+func engineUsed(engine *Engine, usageWeight bool) (int64, int64) {
+
+	usedCpus := engine.UsedCpus()
+	usedMemory := engine.UsedMemory()
+	if usageWeight {
+		if usageCpus := engine.UsageCpus(); usageCpus > usedCpus {
+			usedCpus = usageCpus
+		}
+		if usageMemory := engine.UsageMemory(); usageMemory > usedMemory {
+			usedMemory = usageMemory
 		}
+	}
+	return usedCpus, usedMemory / 1024 / 1024
+}
+
+// selectWeightdEngines returns weighted engines which have room for config, filter records why other engines have no room.
+func selectWeightdEngines(engines []*Engine, config models.Container, usageWeight bool, filter *EnginesFilter) weightedEngines {
 
-		//logger.INFO("[#cluster#] weighted engine %s cpuScore:%d memorySocre:%d weight:%d", engine.IP, cpuScore, memoryScore, cpuScore+memoryScore)
-		if cpuScore <= 100 && memoryScore <= 100 {
-			out = append(out, &WeightedEngine{
-				engine: engine,
-				weight: cpuScore + memoryScore,
-			})
+	out := weightedEngines{}
+	for _, engine := range engines {
+		usedCpus, usedMemory := engineUsed(engine, usageWeight)
+		weight, err := engineWeight(engine.TotalCpus(), engine.TotalMemory(), usedCpus, usedMemory, config)
+		if err != nil {
+			logger.INFO("[#cluster#] weighted engine %s filter, %s.", engine.IP, err.Error())
+			filter.Reject(engine, err.Error())
+			continue
 		}
+
+		//logger.INFO("[#cluster#] weighted engine %s weight:%d", engine.IP, weight)
+		out = append(out, &WeightedEngine{
+			engine: engine,
+			weight: weight,
+		})
 	}
 	return out
 }
@@ -144,6 +144,22 @@ func (c *Controller) SetClusterServerNodeLabels(server string, labels map[string
 	return c.Cluster.SetServerNodeLabels(s, labels)
 }
 
+func (c *Controller) SetClusterServerAvailability(server string, availability string) error {
+
+	s := cluster.ParseServer(server)
+	return c.Cluster.SetServerAvailability(s, availability)
+}
+
+func (c *Controller) GetClusterGroupQuotaUsage(groupid string) (*types.GroupQuotaUsage, error) {
+
+	return c.Cluster.GetGroupQuotaUsage(groupid)
+}
+
+func (c *Controller) SetClusterGroupQuota(groupid string, quota types.GroupQuota) error {
+
+	return c.Cluster.SetGroupQuota(groupid, quota)
+}
+
 func (c *Controller) SetClusterEnableEvent(event string) {
 
 	logger.INFO("[#ctrl#] set cluster enable %s.", event)
@@ -193,11 +209,41 @@ func (c *Controller) CreateClusterContainers(groupid string, instances int, webh
 	return c.Cluster.CreateContainers(groupid, instances, webhooks, placement, config, option)
 }
 
+func (c *Controller) CreateClusterContainersAsync(groupid string, instances int, webhooks types.WebHooks, placement types.Placement, config models.Container, option types.CreateOption) (*types.Operation, error) {
+
+	return c.Cluster.CreateContainersAsync(groupid, instances, webhooks, placement, config, option)
+}
+
 func (c *Controller) UpdateClusterContainers(metaid string, instances int, webhooks types.WebHooks, placement types.Placement, config models.Container, option types.UpdateOption) (*types.CreatedContainers, error) {
 
 	return c.Cluster.UpdateContainers(metaid, instances, webhooks, placement, config, option)
 }
 
+func (c *Controller) UpdateClusterContainersAsync(metaid string, instances int, webhooks types.WebHooks, placement types.Placement, config models.Container, option types.UpdateOption) (*types.Operation, error) {
+
+	return c.Cluster.UpdateContainersAsync(metaid, instances, webhooks, placement, config, option)
+}
+
+func (c *Controller) GetClusterOperation(operationid string) (*types.Operation, error) {
+
+	return c.Cluster.GetOperation(operationid)
+}
+
+func (c *Controller) PlanClusterContainers(groupid string, metaid string, instances int, placement types.Placement, config models.Container) (*types.PlanContainers, error) {
+
+	return c.Cluster.PlanContainers(groupid, metaid, instances, placement, config)
+}
+
+func (c *Controller) RebalanceContainers(metaid string, pause time.Duration) (*types.RebalanceContainers, error) {
+
+	return c.Cluster.RebalanceContainers(metaid, pause)
+}
+
+func (c *Controller) RebalanceGroupContainers(groupid string, pause time.Duration) ([]*types.RebalanceContainers, error) {
+
+	return c.Cluster.RebalanceGroupContainers(groupid, pause)
+}
+
 func (c *Controller) OperateContainers(metaid string, action string) (*types.OperatedContainers, error) {
 
 	return c.Cluster.OperateContainers(metaid, "", action)
@@ -208,9 +254,14 @@ func (c *Controller) OperateContainer(containerid string, action string) (string
 	return c.Cluster.OperateContainer(containerid, action)
 }
 
-func (c *Controller) UpgradeContainers(metaid string, imagetag string) (*types.UpgradeContainers, error) {
+func (c *Controller) UpgradeContainers(metaid string, imagetag string, rolling *types.RollingUpgrade) (*types.UpgradeContainers, error) {
 
-	return c.Cluster.UpgradeContainers(metaid, imagetag)
+	return c.Cluster.UpgradeContainers(metaid, imagetag, rolling)
+}
+
+func (c *Controller) UpgradeContainersAsync(metaid string, imagetag string, rolling *types.RollingUpgrade) (*types.Operation, error) {
+
+	return c.Cluster.UpgradeContainersAsync(metaid, imagetag, rolling)
 }
 
 func (c *Controller) RemoveContainersOfMetaName(groupid string, metaname string) (string, *types.RemovedContainers, error) {
@@ -223,7 +274,27 @@ func (c *Controller) RemoveContainers(metaid string) (*types.RemovedContainers,
 	return c.Cluster.RemoveContainers(metaid, "")
 }
 
+func (c *Controller) RemoveContainersAsync(metaid string) (*types.Operation, error) {
+
+	return c.Cluster.RemoveContainersAsync(metaid, "")
+}
+
 func (c *Controller) RemoveContainer(containerid string) (string, *types.RemovedContainers, error) {
 
 	return c.Cluster.RemoveContainer(containerid)
 }
+
+func (c *Controller) GetClusterMigrations() []*types.Migration {
+
+	return c.Cluster.GetMigrations()
+}
+
+func (c *Controller) CancelClusterMigration(metaid string) error {
+
+	return c.Cluster.CancelMigration(metaid)
+}
+
+func (c *Controller) StartClusterMigration(metaid string) error {
+
+	return c.Cluster.StartMigration(metaid)
+}